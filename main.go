@@ -68,13 +68,18 @@ func newBlockchainRouter(
 	traceRetriever *tools.TraceRetriever,
 	rosettaLib *rosettaFilecoinLib.RosettaConstructionFilecoin,
 ) http.Handler {
-	accountAPIService := rosetta.NewAccountAPIService(network, &api, rosettaLib)
+	accountAPIService := services.NewAccountAPIService(network, &api)
 	accountAPIController := server.NewAccountAPIController(
 		accountAPIService,
 		asserter,
 	)
 
-	networkAPIService := rosetta.NewNetworkAPIService(network, &api, filparser.GetSupportedOps())
+	networkAPIService := services.NewNetworkAPIService(
+		network,
+		rosetta.NewNetworkAPIService(network, &api, filparser.GetSupportedOps()),
+		services.SupportedOperations(rosetta.GetSupportedOpList()),
+		call.Methods.Names(),
+	)
 	networkAPIController := server.NewNetworkAPIController(
 		networkAPIService,
 		asserter,
@@ -86,7 +91,7 @@ func newBlockchainRouter(
 		asserter,
 	)
 
-	callAPIService := call.NewCallAPIService(network, &api, traceRetriever)
+	callAPIService := call.NewCallAPIService(network, &api, traceRetriever, rosettaLib)
 	callAPIController := server.NewCallAPIController(
 		callAPIService,
 		asserter,
@@ -98,7 +103,43 @@ func newBlockchainRouter(
 		asserter,
 	)
 
-	constructionAPIService := services.NewConstructionAPIService(network, &api)
+	constructionAPIService := services.NewConstructionAPIService(network, &api, rosettaLib, services.ConstructionConfig{
+		PreflightSimulation: viper.GetBool("preflight_simulation"),
+		StrictOptions:       viper.GetBool("strict_options"),
+		NonceReservationTTL: viper.GetDuration("nonce_reservation_ttl"),
+	})
+	constructionAPIController := server.NewConstructionAPIController(
+		constructionAPIService,
+		asserter,
+	)
+
+	return server.NewRouter(accountAPIController, networkAPIController, blockAPIController,
+		mempoolAPIController, constructionAPIController, callAPIController)
+}
+
+// newOfflineRouter creates a Mux http.Handler that serves only the endpoints
+// not needing a Lotus node. The rest answer with an "unavailable offline" error.
+func newOfflineRouter(
+	network *types.NetworkIdentifier,
+	asserter *rosettaAsserter.Asserter,
+	rosettaLib *rosettaFilecoinLib.RosettaConstructionFilecoin,
+) http.Handler {
+	var node api.FullNode // there is no node in offline mode
+
+	offlineAPIService := services.NewOfflineAPIService()
+	accountAPIController := server.NewAccountAPIController(offlineAPIService, asserter)
+	blockAPIController := server.NewBlockAPIController(offlineAPIService, asserter)
+	callAPIController := server.NewCallAPIController(offlineAPIService, asserter)
+	mempoolAPIController := server.NewMempoolAPIController(offlineAPIService, asserter)
+
+	// Call methods need a node, none is advertised offline
+	networkAPIService := services.NewNetworkAPIService(network, nil, services.SupportedOperations(rosetta.GetSupportedOpList()), nil)
+	networkAPIController := server.NewNetworkAPIController(
+		networkAPIService,
+		asserter,
+	)
+
+	constructionAPIService := services.NewConstructionAPIService(network, &node, rosettaLib, services.ConstructionConfig{})
 	constructionAPIController := server.NewConstructionAPIController(
 		constructionAPIService,
 		asserter,
@@ -108,20 +149,26 @@ func newBlockchainRouter(
 		mempoolAPIController, constructionAPIController, callAPIController)
 }
 
+// startRosettaRPC serves the rosetta API. A nil api starts the proxy in offline mode.
 func startRosettaRPC(ctx context.Context, api api.FullNode) error {
-	netName, _ := api.StateNetworkName(ctx)
+	networkName := tools.NetworkName
+	if api != nil {
+		netName, _ := api.StateNetworkName(ctx)
+		networkName = string(netName)
+	}
+
 	network := &types.NetworkIdentifier{
 		Blockchain: BlockchainName,
-		Network:    string(netName),
+		Network:    networkName,
 	}
 
 	// The asserter automatically rejects incorrectly formatted
 	// requests.
 	asserter, err := rosettaAsserter.NewServer(
-		rosetta.GetSupportedOpList(),
+		services.SupportedOperations(rosetta.GetSupportedOpList()),
 		true,
 		[]*types.NetworkIdentifier{network},
-		[]string{call.StateComputeCall},
+		call.Methods.Names(),
 		false,
 		"",
 	)
@@ -130,21 +177,27 @@ func startRosettaRPC(ctx context.Context, api api.FullNode) error {
 	}
 
 	// Create instance of RosettaFilecoinLib for current network
+	// Without a node it runs in offline mode
 	r := rosettaFilecoinLib.NewRosettaConstructionFilecoin(api)
 
-	// Build trace retriever
-	retriever := tools.NewTraceRetriever(
-		viper.GetBool("use_cached_traces"),
-		viper.GetString("trace_bucket"),
-		data_store.DataStoreConfig{
-			Url:      viper.GetString("data_store.url"),
-			User:     viper.GetString("data_store.user"),
-			Password: viper.GetString("data_store.password"),
-			Service:  data_store.S3Storage,
-		},
-	)
-
-	router := newBlockchainRouter(network, asserter, api, retriever, r)
+	var router http.Handler
+	if api == nil {
+		router = newOfflineRouter(network, asserter, r)
+	} else {
+		// Build trace retriever
+		retriever := tools.NewTraceRetriever(
+			viper.GetBool("use_cached_traces"),
+			viper.GetString("trace_bucket"),
+			data_store.DataStoreConfig{
+				Url:      viper.GetString("data_store.url"),
+				User:     viper.GetString("data_store.user"),
+				Password: viper.GetString("data_store.password"),
+				Service:  data_store.S3Storage,
+			},
+		)
+
+		router = newBlockchainRouter(network, asserter, api, retriever, r)
+	}
 	loggedRouter := server.LoggerMiddleware(router)
 	corsRouter := server.CorsMiddleware(loggedRouter)
 	server := &http.Server{Addr: fmt.Sprintf(":%d", ServerPort), Handler: corsRouter} //nolint
@@ -207,6 +260,32 @@ func main() {
 	viper.AddConfigPath("/")
 	viper.AddConfigPath(".")
 	viper.SetDefault("use_cached_traces", false)
+	viper.SetDefault("offline_mode", false)
+	viper.SetDefault("preflight_simulation", false)
+	viper.SetDefault("strict_options", false)
+	viper.SetDefault("nonce_reservation_ttl", 0)
+
+	if err := viper.ReadInConfig(); err != nil {
+		rosetta.Logger.Warnf("Could not read config file: %s", err)
+	}
+
+	ctx := context.Background()
+
+	// In offline mode there is no Lotus node to connect to, only node-free endpoints are served
+	if viper.GetBool("offline_mode") {
+		tools.NetworkName = viper.GetString("network_name")
+		if tools.NetworkName == "" {
+			rosetta.Logger.Fatal("network_name must be configured to run in offline mode")
+			return
+		}
+
+		rosetta.Logger.Warnf("Starting in offline mode for network %s", tools.NetworkName)
+		err := startRosettaRPC(ctx, nil)
+		if err != nil {
+			rosetta.Logger.Info("Exit Rosetta rpc", err)
+		}
+		return
+	}
 
 	var lotusAPI api.FullNode
 	var clientCloser jsonrpc.ClientCloser // nolint
@@ -229,7 +308,6 @@ func main() {
 	}
 	defer clientCloser()
 
-	ctx := context.Background()
 	err = startRosettaRPC(ctx, lotusAPI)
 	if err != nil {
 		rosetta.Logger.Info("Exit Rosetta rpc", err)
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const (
+	// LockedSubAccount is the balance of a miner or multisig actor that can not be spent
+	LockedSubAccount = "locked"
+
+	// AvailableSubAccount is the balance of a miner or multisig actor that can be spent
+	AvailableSubAccount = "available"
+
+	// VestingSubAccount is the block rewards of a miner actor still vesting
+	VestingSubAccount = "vesting"
+
+	// PreCommitDepositsSubAccount is the balance of a miner actor held as pre-commit deposits
+	PreCommitDepositsSubAccount = "preCommitDeposits"
+
+	// InitialPledgeSubAccount is the balance of a miner actor held as initial pledge
+	InitialPledgeSubAccount = "initialPledge"
+)
+
+// The upstream account service named the multisig sub-accounts differently.
+// These names are still accepted, so existing clients keep working.
+const (
+	// LegacyLockedBalanceSubAccount is the upstream name of LockedSubAccount
+	LegacyLockedBalanceSubAccount = "LockedBalance"
+
+	// LegacySpendableBalanceSubAccount is the upstream name of AvailableSubAccount
+	LegacySpendableBalanceSubAccount = "SpendableBalance"
+
+	// LegacyVestingScheduleSubAccount is the upstream multisig vesting schedule,
+	// which is not a balance and is rejected with ErrInvalidSubAccount
+	LegacyVestingScheduleSubAccount = "VestingSchedule"
+)
+
+// subAccountAliases maps the upstream sub-account names to the current ones
+var subAccountAliases = map[string]string{
+	LegacyLockedBalanceSubAccount:    LockedSubAccount,
+	LegacySpendableBalanceSubAccount: AvailableSubAccount,
+}
+
+// minerSubAccounts are the sub-accounts supported on miner actors
+var minerSubAccounts = map[string]bool{
+	LockedSubAccount:            true,
+	AvailableSubAccount:         true,
+	VestingSubAccount:           true,
+	PreCommitDepositsSubAccount: true,
+	InitialPledgeSubAccount:     true,
+}
+
+// multisigSubAccounts are the sub-accounts supported on multisig actors
+var multisigSubAccounts = map[string]bool{
+	LockedSubAccount:    true,
+	AvailableSubAccount: true,
+}
+
+// AccountAPIService implements the server.AccountAPIServicer interface.
+type AccountAPIService struct {
+	network *types.NetworkIdentifier
+	node    api.FullNode
+}
+
+// NewAccountAPIService creates a new instance of an AccountAPIService.
+func NewAccountAPIService(network *types.NetworkIdentifier, api *api.FullNode) server.AccountAPIServicer {
+	return &AccountAPIService{
+		network: network,
+		node:    *api,
+	}
+}
+
+// AccountBalance implements the /account/balance endpoint. Without a
+// sub-account the whole balance of the actor is returned, without metadata.
+// Miner and multisig actors also support sub-accounts splitting their balance.
+func (s *AccountAPIService) AccountBalance(
+	ctx context.Context,
+	request *types.AccountBalanceRequest,
+) (*types.AccountBalanceResponse, *types.Error) {
+
+	errNet := rosetta.ValidateNetworkId(ctx, &s.node, request.NetworkIdentifier)
+	if errNet != nil {
+		return nil, errNet
+	}
+
+	if request.AccountIdentifier == nil {
+		return nil, rosetta.BuildError(rosetta.ErrInsufficientQueryInputs, nil, false)
+	}
+
+	addr, err := address.NewFromString(request.AccountIdentifier.Address)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrInvalidAccountAddress, err, false)
+	}
+
+	// Check sync status
+	status, syncErr := rosetta.CheckSyncStatus(ctx, &s.node)
+	if syncErr != nil {
+		return nil, syncErr
+	}
+	if !status.IsSynced() {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetUnsyncedBlock, nil, true)
+	}
+
+	tipSet, errTipSet := ResolveTipSet(ctx, s.node, request.BlockIdentifier)
+	if errTipSet != nil {
+		return nil, errTipSet
+	}
+
+	tipSetKeyHash, err := rosetta.BuildTipSetKeyHash(tipSet.Key())
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToBuildTipSetHash, err, true)
+	}
+
+	var actor *filTypes.Actor
+	impl := func() {
+		actor, err = s.node.StateGetActor(ctx, addr, tipSet.Key())
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	balance := big.Zero()
+	if err != nil {
+		// Addresses without an actor have never received funds
+		if !strings.Contains(err.Error(), filTypes.ErrActorNotFound.Error()) {
+			return nil, rosetta.BuildError(ErrUnableToGetActor, err, true)
+		}
+	} else {
+		balance = actor.Balance
+	}
+
+	if subAccount := request.AccountIdentifier.SubAccount; subAccount != nil {
+		var errSub *types.Error
+		balance, errSub = s.subAccountBalance(ctx, addr, actor, subAccount.Address, tipSet.Key())
+		if errSub != nil {
+			return nil, errSub
+		}
+	}
+
+	return &types.AccountBalanceResponse{
+		BlockIdentifier: &types.BlockIdentifier{
+			Index: int64(tipSet.Height()),
+			Hash:  *tipSetKeyHash,
+		},
+		Balances: []*types.Amount{
+			{
+				Value:    balance.String(),
+				Currency: rosetta.GetCurrencyData(),
+			},
+		},
+	}, nil
+}
+
+// subAccountBalance returns the part of the balance of actor held in a sub-account
+func (s *AccountAPIService) subAccountBalance(ctx context.Context, addr address.Address,
+	actor *filTypes.Actor, subAccount string, tsk filTypes.TipSetKey) (abi.TokenAmount, *types.Error) {
+
+	var (
+		balance abi.TokenAmount
+		err     error
+	)
+
+	if subAccount == LegacyVestingScheduleSubAccount {
+		return big.Zero(), rosetta.BuildError(ErrInvalidSubAccount,
+			fmt.Errorf("sub-account %s is no longer supported, use %s instead", subAccount, LockedSubAccount), false)
+	}
+
+	if alias, ok := subAccountAliases[subAccount]; ok {
+		subAccount = alias
+	}
+
+	switch {
+	case actor != nil && builtin.IsStorageMinerActor(actor.Code) && minerSubAccounts[subAccount]:
+		balance, err = s.minerSubAccountBalance(ctx, addr, subAccount, tsk)
+	case actor != nil && builtin.IsMultisigActor(actor.Code) && multisigSubAccounts[subAccount]:
+		balance, err = s.multisigSubAccountBalance(ctx, addr, actor, subAccount, tsk)
+	default:
+		return big.Zero(), rosetta.BuildError(ErrInvalidSubAccount,
+			fmt.Errorf("sub-account %s is not supported for %s", subAccount, addr), false)
+	}
+
+	if err != nil {
+		return big.Zero(), rosetta.BuildError(ErrUnableToGetActor, err, true)
+	}
+
+	return balance, nil
+}
+
+// minerSubAccountBalance returns a sub-account balance of a miner actor
+func (s *AccountAPIService) minerSubAccountBalance(ctx context.Context, addr address.Address,
+	subAccount string, tsk filTypes.TipSetKey) (abi.TokenAmount, error) {
+
+	var (
+		available abi.TokenAmount
+		state     *api.ActorState
+		err       error
+	)
+	impl := func() {
+		if subAccount == AvailableSubAccount {
+			available, err = s.node.StateMinerAvailableBalance(ctx, addr, tsk)
+			return
+		}
+		state, err = s.node.StateReadState(ctx, addr, tsk)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, LotusCallTimeOut)
+	if errTimeOut != nil {
+		return big.Zero(), errTimeOut
+	}
+	if err != nil {
+		return big.Zero(), err
+	}
+
+	if subAccount == AvailableSubAccount {
+		return available, nil
+	}
+
+	locked, err := ReadMinerLockedFunds(state)
+	if err != nil {
+		return big.Zero(), err
+	}
+
+	switch subAccount {
+	case VestingSubAccount:
+		return locked.LockedFunds, nil
+	case PreCommitDepositsSubAccount:
+		return locked.PreCommitDeposits, nil
+	case InitialPledgeSubAccount:
+		return locked.InitialPledge, nil
+	default:
+		return locked.Total(), nil
+	}
+}
+
+// multisigSubAccountBalance returns a sub-account balance of a multisig actor
+func (s *AccountAPIService) multisigSubAccountBalance(ctx context.Context, addr address.Address,
+	actor *filTypes.Actor, subAccount string, tsk filTypes.TipSetKey) (abi.TokenAmount, error) {
+
+	var (
+		available abi.TokenAmount
+		err       error
+	)
+	impl := func() {
+		available, err = s.node.MsigGetAvailableBalance(ctx, addr, tsk)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, LotusCallTimeOut)
+	if errTimeOut != nil {
+		return big.Zero(), errTimeOut
+	}
+	if err != nil {
+		return big.Zero(), err
+	}
+
+	if subAccount == AvailableSubAccount {
+		return available, nil
+	}
+	return big.Sub(actor.Balance, available), nil
+}
+
+// AccountCoins implements the /account/coins endpoint. Filecoin is account
+// based, so there are no coins to return.
+func (s *AccountAPIService) AccountCoins(
+	ctx context.Context,
+	request *types.AccountCoinsRequest,
+) (*types.AccountCoinsResponse, *types.Error) {
+	return nil, rosetta.ErrNotImplemented
+}
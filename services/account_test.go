@@ -0,0 +1,165 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	actorstypes "github.com/filecoin-project/go-state-types/actors"
+	"github.com/filecoin-project/go-state-types/manifest"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tests/fixtures"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tests/mocks"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+func newAccountService(t *testing.T) (*services.AccountAPIService, *mocks.FullNode, *filTypes.TipSet) {
+	ts := fixtures.TipSet(t, 100)
+	fullNodeMock := fixtures.NewFullNode(t)
+	fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, ts.Height(), mock.Anything).Return(ts, nil).Maybe()
+
+	var node api.FullNode = fullNodeMock
+	s := services.NewAccountAPIService(testNetwork, &node).(*services.AccountAPIService)
+	return s, fullNodeMock, ts
+}
+
+func accountBalanceRequest(addr string, subAccount string) *types.AccountBalanceRequest {
+	request := &types.AccountBalanceRequest{
+		NetworkIdentifier: testNetwork,
+		AccountIdentifier: &types.AccountIdentifier{Address: addr},
+		BlockIdentifier:   &types.PartialBlockIdentifier{Index: types.Int64(100)},
+	}
+	if subAccount != "" {
+		request.AccountIdentifier.SubAccount = &types.SubAccountIdentifier{Address: subAccount}
+	}
+	return request
+}
+
+func TestAccountBalance(t *testing.T) {
+	addr, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	code, ok := actors.GetActorCodeID(actorstypes.Version16, manifest.AccountKey)
+	require.True(t, ok)
+
+	s, fullNodeMock, ts := newAccountService(t)
+	fullNodeMock.On("StateGetActor", mock.Anything, addr, ts.Key()).Return(&filTypes.Actor{
+		Code:    code,
+		Balance: abi.NewTokenAmount(1000),
+	}, nil)
+
+	resp, rosettaErr := s.AccountBalance(context.Background(), accountBalanceRequest("f01001", ""))
+	require.Nil(t, rosettaErr)
+
+	// The plain-address response holds the block, a single FIL balance and no metadata
+	tipSetHash, err := rosetta.BuildTipSetKeyHash(ts.Key())
+	require.NoError(t, err)
+	assert.Equal(t, &types.AccountBalanceResponse{
+		BlockIdentifier: &types.BlockIdentifier{Index: 100, Hash: *tipSetHash},
+		Balances: []*types.Amount{
+			{Value: "1000", Currency: rosetta.GetCurrencyData()},
+		},
+	}, resp)
+
+	_, rosettaErr = s.AccountBalance(context.Background(), accountBalanceRequest("f01001", services.LockedSubAccount))
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrInvalidSubAccount.Code, rosettaErr.Code)
+}
+
+func TestAccountBalanceActorNotFound(t *testing.T) {
+	addr, err := address.NewIDAddress(1003)
+	require.NoError(t, err)
+
+	s, fullNodeMock, ts := newAccountService(t)
+	fullNodeMock.On("StateGetActor", mock.Anything, addr, ts.Key()).Return(nil, filTypes.ErrActorNotFound)
+
+	resp, rosettaErr := s.AccountBalance(context.Background(), accountBalanceRequest("f01003", ""))
+	require.Nil(t, rosettaErr)
+	require.Len(t, resp.Balances, 1)
+	assert.Equal(t, "0", resp.Balances[0].Value)
+	assert.Nil(t, resp.Metadata)
+}
+
+func TestAccountBalanceMinerSubAccounts(t *testing.T) {
+	addr, err := address.NewIDAddress(1234)
+	require.NoError(t, err)
+	code, ok := actors.GetActorCodeID(actorstypes.Version16, manifest.MinerKey)
+	require.True(t, ok)
+
+	s, fullNodeMock, ts := newAccountService(t)
+	fullNodeMock.On("StateGetActor", mock.Anything, addr, ts.Key()).Return(&filTypes.Actor{
+		Code:    code,
+		Balance: abi.NewTokenAmount(1000),
+	}, nil)
+	fullNodeMock.On("StateMinerAvailableBalance", mock.Anything, addr, ts.Key()).Return(abi.NewTokenAmount(180), nil).Maybe()
+	fullNodeMock.On("StateReadState", mock.Anything, addr, ts.Key()).Return(&api.ActorState{
+		Balance: abi.NewTokenAmount(1000),
+		State: map[string]interface{}{
+			"LockedFunds":       "300",
+			"PreCommitDeposits": "20",
+			"InitialPledge":     "500",
+			"FeeDebt":           "0",
+		},
+	}, nil).Maybe()
+
+	expected := map[string]string{
+		"":                                   "1000",
+		services.LockedSubAccount:            "820",
+		services.AvailableSubAccount:         "180",
+		services.VestingSubAccount:           "300",
+		services.PreCommitDepositsSubAccount: "20",
+		services.InitialPledgeSubAccount:     "500",
+	}
+	for subAccount, balance := range expected {
+		resp, rosettaErr := s.AccountBalance(context.Background(), accountBalanceRequest("f01234", subAccount))
+		require.Nil(t, rosettaErr, subAccount)
+		assert.Equal(t, balance, resp.Balances[0].Value, subAccount)
+	}
+}
+
+func TestAccountBalanceMultisigSubAccounts(t *testing.T) {
+	addr, err := address.NewIDAddress(1002)
+	require.NoError(t, err)
+	code, ok := actors.GetActorCodeID(actorstypes.Version16, manifest.MultisigKey)
+	require.True(t, ok)
+
+	s, fullNodeMock, ts := newAccountService(t)
+	fullNodeMock.On("StateGetActor", mock.Anything, addr, ts.Key()).Return(&filTypes.Actor{
+		Code:    code,
+		Balance: abi.NewTokenAmount(1000),
+	}, nil)
+	fullNodeMock.On("MsigGetAvailableBalance", mock.Anything, addr, ts.Key()).Return(abi.NewTokenAmount(400), nil)
+
+	resp, rosettaErr := s.AccountBalance(context.Background(), accountBalanceRequest("f01002", services.LockedSubAccount))
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, "600", resp.Balances[0].Value)
+
+	resp, rosettaErr = s.AccountBalance(context.Background(), accountBalanceRequest("f01002", services.AvailableSubAccount))
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, "400", resp.Balances[0].Value)
+
+	// The upstream sub-account names are still accepted
+	resp, rosettaErr = s.AccountBalance(context.Background(), accountBalanceRequest("f01002", services.LegacyLockedBalanceSubAccount))
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, "600", resp.Balances[0].Value)
+
+	resp, rosettaErr = s.AccountBalance(context.Background(), accountBalanceRequest("f01002", services.LegacySpendableBalanceSubAccount))
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, "400", resp.Balances[0].Value)
+
+	_, rosettaErr = s.AccountBalance(context.Background(), accountBalanceRequest("f01002", services.VestingSubAccount))
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrInvalidSubAccount.Code, rosettaErr.Code)
+
+	// The upstream vesting schedule is not a balance and is rejected explicitly
+	_, rosettaErr = s.AccountBalance(context.Background(), accountBalanceRequest("f01002", services.LegacyVestingScheduleSubAccount))
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrInvalidSubAccount.Code, rosettaErr.Code)
+}
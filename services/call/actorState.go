@@ -0,0 +1,139 @@
+package call
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const ActorStateCall = "ActorState"
+
+var actorStateMethod = Method{
+	Name: ActorStateCall,
+	Params: []Param{
+		{Name: "address", Type: ParamString, Required: true},
+		{Name: "blockIdentifier", Type: ParamObject},
+	},
+	Handler: (*CallAPIService).ActorState,
+}
+
+// actorStateParams are the parameters of an ActorState call
+type actorStateParams struct {
+	Address string `json:"address"`
+	// BlockIdentifier defaults to the head tipset
+	BlockIdentifier *rosettaTypes.PartialBlockIdentifier `json:"blockIdentifier"`
+}
+
+// ActorState returns the code, balance, nonce and decoded state of an actor
+func (s *CallAPIService) ActorState(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params actorStateParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	addr, err := address.NewFromString(params.Address)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrInvalidAccountAddress, err, false)
+	}
+
+	tipSet, errTipSet := s.resolveTipSet(ctx, params.BlockIdentifier)
+	if errTipSet != nil {
+		return nil, errTipSet
+	}
+
+	var (
+		actor *filTypes.Actor
+		state *api.ActorState
+	)
+	impl := func() {
+		actor, err = s.node.StateGetActor(ctx, addr, tipSet.Key())
+		if err != nil {
+			return
+		}
+		state, err = s.node.StateReadState(ctx, addr, tipSet.Key())
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, actorError(err)
+	}
+
+	var decodedState map[string]interface{}
+	raw, err := json.Marshal(state.State)
+	if err == nil {
+		err = json.Unmarshal(raw, &decodedState)
+	}
+	if err != nil {
+		return nil, rosetta.BuildError(services.ErrUnableToGetActor, err, true)
+	}
+
+	blockId, errBlock := blockIdentifier(tipSet)
+	if errBlock != nil {
+		return nil, errBlock
+	}
+
+	result := map[string]interface{}{
+		"blockIdentifier": map[string]interface{}{
+			"index": blockId.Index,
+			"hash":  blockId.Hash,
+		},
+		"code":    actor.Code.String(),
+		"balance": actor.Balance.String(),
+		"nonce":   actor.Nonce,
+		"state":   decodedState,
+	}
+	for key, value := range actorCodeInfo(actor.Code) {
+		result[key] = value
+	}
+	if actor.DelegatedAddress != nil {
+		result["delegatedAddress"] = actor.DelegatedAddress.String()
+	}
+
+	return &rosettaTypes.CallResponse{
+		Result:     result,
+		Idempotent: params.BlockIdentifier != nil && params.BlockIdentifier.Index != nil,
+	}, nil
+}
+
+// actorCodeInfo returns the builtin actor name and version of a code CID. The
+// version is only known for actors deployed through a manifest (v8 onwards).
+func actorCodeInfo(code cid.Cid) map[string]interface{} {
+	if name, version, ok := actors.GetActorMetaByCode(code); ok {
+		return map[string]interface{}{
+			"actorName":    name,
+			"actorVersion": int64(version),
+		}
+	}
+
+	return map[string]interface{}{
+		"actorName": actors.CanonicalName(builtin.ActorNameByCode(code)),
+	}
+}
+
+// actorError maps the errors of the Lotus state calls, telling apart actors
+// that do not exist
+func actorError(err error) *rosettaTypes.Error {
+	if strings.Contains(err.Error(), filTypes.ErrActorNotFound.Error()) {
+		return rosetta.BuildError(services.ErrActorNotFound, err, false)
+	}
+	return rosetta.BuildError(services.ErrUnableToGetActor, err, true)
+}
@@ -0,0 +1,71 @@
+package call_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	actorstypes "github.com/filecoin-project/go-state-types/actors"
+	"github.com/filecoin-project/go-state-types/manifest"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+)
+
+func TestActorState(t *testing.T) {
+	addr, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	code, ok := actors.GetActorCodeID(actorstypes.Version16, manifest.MultisigKey)
+	require.True(t, ok)
+
+	head := testTipSet(t, 100)
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("ChainHead", mock.Anything).Return(head, nil)
+	fullNodeMock.On("StateGetActor", mock.Anything, addr, head.Key()).Return(&filTypes.Actor{
+		Code:    code,
+		Nonce:   3,
+		Balance: abi.NewTokenAmount(500),
+	}, nil)
+	fullNodeMock.On("StateReadState", mock.Anything, addr, head.Key()).Return(&api.ActorState{
+		Balance: abi.NewTokenAmount(500),
+		Code:    code,
+		State:   map[string]interface{}{"NumApprovalsThreshold": 2},
+	}, nil)
+
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.ActorStateCall,
+		Parameters:        map[string]interface{}{"address": "f01001"},
+	})
+	require.Nil(t, rosettaErr)
+	assert.False(t, resp.Idempotent)
+	assert.Equal(t, manifest.MultisigKey, resp.Result["actorName"])
+	assert.Equal(t, int64(actorstypes.Version16), resp.Result["actorVersion"])
+	assert.Equal(t, "500", resp.Result["balance"])
+	assert.Equal(t, uint64(3), resp.Result["nonce"])
+	assert.Equal(t, float64(2), resp.Result["state"].(map[string]interface{})["NumApprovalsThreshold"])
+}
+
+func TestActorStateNotFound(t *testing.T) {
+	head := testTipSet(t, 100)
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("ChainHead", mock.Anything).Return(head, nil)
+	fullNodeMock.On("StateGetActor", mock.Anything, mock.Anything, head.Key()).
+		Return(nil, errors.New("resolution lookup failed (f01001): actor not found"))
+
+	_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.ActorStateCall,
+		Parameters:        map[string]interface{}{"address": "f01001"},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrActorNotFound.Code, rosettaErr.Code)
+}
@@ -6,7 +6,10 @@ import (
 	"github.com/coinbase/rosetta-sdk-go/server"
 	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
 	"github.com/filecoin-project/lotus/api"
+	filparser "github.com/zondax/fil-parser"
+	"github.com/zondax/fil-parser/actors/cache/impl/common"
 	"github.com/zondax/filecoin-indexing-rosetta-proxy/tools"
+	filLib "github.com/zondax/rosetta-filecoin-lib"
 	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
 )
 
@@ -14,15 +17,35 @@ type CallAPIService struct {
 	network        *rosettaTypes.NetworkIdentifier
 	node           api.FullNode
 	traceRetriever *tools.TraceRetriever
+	// p decodes params and returns, nil when no rosetta lib is given or it
+	// could not be created
+	p *filparser.FilecoinParser
+	// parserErr is the error creating p, reported by the calls needing it
+	parserErr error
 }
 
 // NewCallAPIService creates a new instance of a CallAPIService.
 // nolint
-func NewCallAPIService(network *rosettaTypes.NetworkIdentifier, api *api.FullNode, retriever *tools.TraceRetriever) server.CallAPIServicer {
+func NewCallAPIService(network *rosettaTypes.NetworkIdentifier, api *api.FullNode, retriever *tools.TraceRetriever,
+	r *filLib.RosettaConstructionFilecoin) server.CallAPIServicer {
+	var (
+		parser    *filparser.FilecoinParser
+		parserErr error
+	)
+	if r != nil {
+		parser, parserErr = filparser.NewFilecoinParser(r, common.DataSource{Node: *api}, nil)
+		if parserErr != nil {
+			rosetta.Logger.Errorf("unable to create the parser, calls parsing transactions will fail: %v", parserErr)
+			parser = nil
+		}
+	}
+
 	return &CallAPIService{
 		network:        network,
 		node:           *api,
 		traceRetriever: retriever,
+		p:              parser,
+		parserErr:      parserErr,
 	}
 }
 
@@ -32,10 +55,5 @@ func (s *CallAPIService) Call(ctx context.Context, request *rosettaTypes.CallReq
 		return nil, errNet
 	}
 
-	switch request.Method {
-	case StateComputeCall:
-		return s.StateComputeVersioned(ctx, request)
-	default:
-		return nil, rosetta.BuildError(rosetta.ErrOperationNotSupported, nil, true)
-	}
+	return s.dispatch(ctx, request)
 }
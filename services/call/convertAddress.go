@@ -0,0 +1,134 @@
+package call
+
+import (
+	"context"
+	"strings"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const ConvertAddressCall = "ConvertAddress"
+
+var convertAddressMethod = Method{
+	Name: ConvertAddressCall,
+	Params: []Param{
+		{Name: "address", Type: ParamString, Required: true},
+		{Name: "blockIdentifier", Type: ParamObject},
+	},
+	Handler: (*CallAPIService).ConvertAddress,
+}
+
+// convertAddressParams are the parameters of a ConvertAddress call
+type convertAddressParams struct {
+	// Address is a Filecoin address of any protocol or a 0x Ethereum address
+	Address string `json:"address"`
+	// BlockIdentifier defaults to the head tipset
+	BlockIdentifier *rosettaTypes.PartialBlockIdentifier `json:"blockIdentifier"`
+}
+
+// ConvertAddress returns every form of the address of an actor: its ID, its
+// robust (f1, f2 or f3) address, its delegated (f410) address and its
+// Ethereum address. Forms the actor does not have are left out.
+func (s *CallAPIService) ConvertAddress(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params convertAddressParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	addr, err := parseAnyAddress(params.Address)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrInvalidAccountAddress, err, false)
+	}
+
+	tipSet, errTipSet := s.resolveTipSet(ctx, params.BlockIdentifier)
+	if errTipSet != nil {
+		return nil, errTipSet
+	}
+
+	var (
+		id     address.Address
+		actor  *filTypes.Actor
+		robust address.Address
+	)
+	impl := func() {
+		id, err = s.node.StateLookupID(ctx, addr, tipSet.Key())
+		if err != nil {
+			return
+		}
+		actor, err = s.node.StateGetActor(ctx, id, tipSet.Key())
+		if err != nil {
+			return
+		}
+		switch {
+		case builtin.IsAccountActor(actor.Code):
+			robust, err = s.node.StateAccountKey(ctx, id, tipSet.Key())
+		case addr.Protocol() == address.Actor:
+			robust = addr
+		default:
+			// Singletons and actors created before robust addresses have none
+			var lookupErr error
+			robust, lookupErr = s.node.StateLookupRobustAddress(ctx, id, tipSet.Key())
+			if lookupErr != nil {
+				robust = address.Undef
+			}
+		}
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, actorError(err)
+	}
+
+	result := map[string]interface{}{
+		"id": id.String(),
+	}
+	if robust != address.Undef {
+		result["robust"] = robust.String()
+	}
+
+	// Actors without a delegated address are reachable from the EVM through
+	// their masked ID address
+	ethSource := id
+	if actor.DelegatedAddress != nil {
+		result["delegated"] = actor.DelegatedAddress.String()
+		ethSource = *actor.DelegatedAddress
+	}
+	ethAddr, err := ethtypes.EthAddressFromFilecoinAddress(ethSource)
+	if err == nil {
+		result["ethAddress"] = ethAddr.String()
+	}
+
+	return &rosettaTypes.CallResponse{
+		Result:     result,
+		Idempotent: params.BlockIdentifier != nil && params.BlockIdentifier.Index != nil,
+	}, nil
+}
+
+// parseAnyAddress parses a Filecoin address or a 0x Ethereum address, which is
+// converted to its delegated address, or to its ID address when it is masked
+func parseAnyAddress(s string) (address.Address, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return address.NewFromString(s)
+	}
+
+	ethAddr, err := ethtypes.ParseEthAddress(s)
+	if err != nil {
+		return address.Undef, err
+	}
+	return ethAddr.ToFilecoinAddress()
+}
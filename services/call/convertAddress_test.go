@@ -0,0 +1,88 @@
+package call_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	actorstypes "github.com/filecoin-project/go-state-types/actors"
+	"github.com/filecoin-project/go-state-types/manifest"
+	"github.com/filecoin-project/lotus/chain/actors"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+)
+
+func TestConvertAddress(t *testing.T) {
+	id, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	ethAddr, err := ethtypes.ParseEthAddress("0xd4c5fb16488aa48081296299d54b0c648c9333da")
+	require.NoError(t, err)
+	delegated, err := ethAddr.ToFilecoinAddress()
+	require.NoError(t, err)
+	accountCode, ok := actors.GetActorCodeID(actorstypes.Version16, manifest.AccountKey)
+	require.True(t, ok)
+	evmCode, ok := actors.GetActorCodeID(actorstypes.Version16, manifest.EvmKey)
+	require.True(t, ok)
+	robust, err := address.NewFromString("f1abjxfbp274xpdqcpuaykwkfb43omjotacm2p3za")
+	require.NoError(t, err)
+
+	head := testTipSet(t, 100)
+
+	t.Run("ethereum address", func(t *testing.T) {
+		s, fullNodeMock := newCallService(t)
+		fullNodeMock.On("ChainHead", mock.Anything).Return(head, nil)
+		fullNodeMock.On("StateLookupID", mock.Anything, delegated, head.Key()).Return(id, nil)
+		fullNodeMock.On("StateGetActor", mock.Anything, id, head.Key()).Return(&filTypes.Actor{Code: evmCode, DelegatedAddress: &delegated}, nil)
+		fullNodeMock.On("StateLookupRobustAddress", mock.Anything, id, head.Key()).Return(address.Undef, errors.New("no robust address"))
+
+		resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+			NetworkIdentifier: testNetwork,
+			Method:            call.ConvertAddressCall,
+			Parameters:        map[string]interface{}{"address": ethAddr.String()},
+		})
+		require.Nil(t, rosettaErr)
+		assert.Equal(t, map[string]interface{}{
+			"id":         id.String(),
+			"delegated":  delegated.String(),
+			"ethAddress": ethAddr.String(),
+		}, resp.Result)
+	})
+
+	t.Run("account ID", func(t *testing.T) {
+		s, fullNodeMock := newCallService(t)
+		fullNodeMock.On("ChainHead", mock.Anything).Return(head, nil)
+		fullNodeMock.On("StateLookupID", mock.Anything, id, head.Key()).Return(id, nil)
+		fullNodeMock.On("StateGetActor", mock.Anything, id, head.Key()).Return(&filTypes.Actor{Code: accountCode}, nil)
+		fullNodeMock.On("StateAccountKey", mock.Anything, id, head.Key()).Return(robust, nil)
+
+		resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+			NetworkIdentifier: testNetwork,
+			Method:            call.ConvertAddressCall,
+			Parameters:        map[string]interface{}{"address": "f01001"},
+		})
+		require.Nil(t, rosettaErr)
+		assert.Equal(t, robust.String(), resp.Result["robust"])
+		assert.Equal(t, "0xff000000000000000000000000000000000003e9", resp.Result["ethAddress"])
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s, fullNodeMock := newCallService(t)
+		fullNodeMock.On("ChainHead", mock.Anything).Return(head, nil)
+		fullNodeMock.On("StateLookupID", mock.Anything, robust, head.Key()).Return(address.Undef, errors.New("actor not found"))
+
+		_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+			NetworkIdentifier: testNetwork,
+			Method:            call.ConvertAddressCall,
+			Parameters:        map[string]interface{}{"address": robust.String()},
+		})
+		require.NotNil(t, rosettaErr)
+		assert.Equal(t, services.ErrActorNotFound.Code, rosettaErr.Code)
+	})
+}
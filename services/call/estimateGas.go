@@ -0,0 +1,135 @@
+package call
+
+import (
+	"context"
+	"fmt"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const EstimateGasCall = "EstimateGas"
+
+var estimateGasMethod = Method{
+	Name: EstimateGasCall,
+	Params: []Param{
+		{Name: "from", Type: ParamString, Required: true},
+		{Name: "to", Type: ParamString, Required: true},
+		{Name: "value", Type: ParamString},
+		{Name: "method", Type: ParamNumber},
+		{Name: "params", Type: ParamString},
+		{Name: "gasLimit", Type: ParamNumber},
+		{Name: "gasPremium", Type: ParamString},
+		{Name: "gasFeeCap", Type: ParamString},
+		{Name: "blockIncl", Type: ParamNumber},
+		{Name: "maxFee", Type: ParamString},
+	},
+	Handler: (*CallAPIService).EstimateGas,
+}
+
+// MaxFeeKey is the name of the key in the result of an EstimateGas call that
+// specifies the most the message can cost, in attoFIL
+const MaxFeeKey = "maxFee"
+
+// estimateGasParams are the parameters of an EstimateGas call. The gas values
+// that are set are kept, the others are estimated.
+type estimateGasParams struct {
+	messageParams
+	GasLimit   int64  `json:"gasLimit"`
+	GasPremium string `json:"gasPremium"`
+	GasFeeCap  string `json:"gasFeeCap"`
+	// BlockIncl defaults to 1
+	BlockIncl uint64 `json:"blockIncl"`
+	// MaxFee in attoFIL, the Lotus default when not set
+	MaxFee string `json:"maxFee"`
+}
+
+// EstimateGas estimates the gas values of an unsigned message the same way
+// /construction/metadata does
+func (s *CallAPIService) EstimateGas(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params estimateGasParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	message, errMsg := params.message()
+	if errMsg != nil {
+		return nil, errMsg
+	}
+
+	if params.GasLimit < 0 {
+		return nil, invalidParam("gasLimit", fmt.Errorf("must not be negative"))
+	}
+	message.GasLimit = params.GasLimit
+
+	var err error
+	message.GasPremium, err = optionalAmount(params.GasPremium)
+	if err != nil {
+		return nil, invalidParam("gasPremium", err)
+	}
+	message.GasFeeCap, err = optionalAmount(params.GasFeeCap)
+	if err != nil {
+		return nil, invalidParam("gasFeeCap", err)
+	}
+	maxFee, err := optionalAmount(params.MaxFee)
+	if err != nil {
+		return nil, invalidParam("maxFee", err)
+	}
+
+	blockIncl := params.BlockIncl
+	if blockIncl == 0 {
+		blockIncl = 1
+	}
+
+	var (
+		estimated *filTypes.Message
+		errGas    *rosettaTypes.Error
+	)
+	impl := func() {
+		estimated, errGas = services.EstimateMessageGas(ctx, s.node, message, blockIncl, maxFee)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if errGas != nil {
+		return nil, errGas
+	}
+
+	return &rosettaTypes.CallResponse{
+		Result: map[string]interface{}{
+			services.GasLimitKey:   estimated.GasLimit,
+			services.GasPremiumKey: estimated.GasPremium.String(),
+			services.GasFeeCapKey:  estimated.GasFeeCap.String(),
+			MaxFeeKey:              big.Mul(estimated.GasFeeCap, big.NewInt(estimated.GasLimit)).String(),
+		},
+		Idempotent: false,
+	}, nil
+}
+
+// optionalAmount parses a non negative attoFIL amount, zero when not set
+func optionalAmount(value string) (abi.TokenAmount, error) {
+	if value == "" {
+		return big.Zero(), nil
+	}
+
+	amount, err := filTypes.BigFromString(value)
+	if err != nil {
+		return big.Zero(), err
+	}
+	if amount.Sign() < 0 {
+		return big.Zero(), fmt.Errorf("must not be negative")
+	}
+	return amount, nil
+}
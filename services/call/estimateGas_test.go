@@ -0,0 +1,64 @@
+package call_test
+
+import (
+	"context"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+func TestEstimateGas(t *testing.T) {
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("GasEstimateMessageGas", mock.Anything, mock.MatchedBy(func(msg *filTypes.Message) bool {
+		return msg.From.String() == "f01001" && msg.GasPremium.Equals(abi.NewTokenAmount(300))
+	}), mock.Anything, filTypes.TipSetKey{}).Return(func(_ context.Context, msg *filTypes.Message, _ *api.MessageSendSpec, _ filTypes.TipSetKey) *filTypes.Message {
+		estimated := *msg
+		estimated.GasLimit = 1000
+		estimated.GasFeeCap = abi.NewTokenAmount(500)
+		return &estimated
+	}, nil)
+	fullNodeMock.On("GasEstimateGasPremium", mock.Anything, uint64(5), mock.Anything, int64(1000), filTypes.TipSetKey{}).
+		Return(abi.NewTokenAmount(200), nil)
+	fullNodeMock.On("GasEstimateFeeCap", mock.Anything, mock.Anything, int64(5), filTypes.TipSetKey{}).
+		Return(abi.NewTokenAmount(400), nil)
+
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.EstimateGasCall,
+		Parameters: map[string]interface{}{
+			"from":       "f01001",
+			"to":         "f01002",
+			"value":      "10",
+			"gasPremium": "300",
+			"blockIncl":  5,
+		},
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, int64(1000), resp.Result[services.GasLimitKey])
+	assert.Equal(t, "300", resp.Result[services.GasPremiumKey])
+	assert.Equal(t, "400", resp.Result[services.GasFeeCapKey])
+	assert.Equal(t, "400000", resp.Result[call.MaxFeeKey])
+}
+
+func TestEstimateGasError(t *testing.T) {
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("GasEstimateMessageGas", mock.Anything, mock.Anything, mock.Anything, filTypes.TipSetKey{}).
+		Return(nil, assert.AnError)
+
+	_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.EstimateGasCall,
+		Parameters:        map[string]interface{}{"from": "f01001", "to": "f01002"},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, rosetta.ErrUnableToEstimateGasLimit.Code, rosettaErr.Code)
+}
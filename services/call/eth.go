@@ -0,0 +1,271 @@
+package call
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const (
+	EthGetTransactionReceiptCall = "EthGetTransactionReceipt"
+	EthGetTransactionByHashCall  = "EthGetTransactionByHash"
+	EthCallCall                  = "EthCall"
+	EthGetLogsCall               = "EthGetLogs"
+)
+
+var ethGetTransactionReceiptMethod = Method{
+	Name: EthGetTransactionReceiptCall,
+	Params: []Param{
+		{Name: "hash", Type: ParamString, Required: true},
+	},
+	Handler: (*CallAPIService).EthGetTransactionReceipt,
+}
+
+var ethGetTransactionByHashMethod = Method{
+	Name: EthGetTransactionByHashCall,
+	Params: []Param{
+		{Name: "hash", Type: ParamString, Required: true},
+	},
+	Handler: (*CallAPIService).EthGetTransactionByHash,
+}
+
+var ethCallMethod = Method{
+	Name: EthCallCall,
+	Params: []Param{
+		{Name: "transaction", Type: ParamObject, Required: true},
+		{Name: "block", Type: ParamStringOrObject},
+	},
+	Handler: (*CallAPIService).EthCall,
+}
+
+var ethGetLogsMethod = Method{
+	Name: EthGetLogsCall,
+	Params: []Param{
+		{Name: "filter", Type: ParamObject, Required: true},
+	},
+	Handler: (*CallAPIService).EthGetLogs,
+}
+
+const (
+	// RevertDataKey is the name of the key in the Details map of an
+	// ErrExecutionReverted error that specifies the 0x prefixed revert data
+	RevertDataKey = "data"
+
+	// RevertReasonKey is the name of the key in the Details map of an
+	// ErrExecutionReverted error that specifies the decoded Error(string) reason
+	RevertReasonKey = "revertReason"
+)
+
+// ethHashParams are the parameters of the calls looking up a transaction
+type ethHashParams struct {
+	Hash ethtypes.EthHash `json:"hash"`
+}
+
+// ethCallParams are the parameters of an EthCall call
+type ethCallParams struct {
+	Transaction ethtypes.EthCall `json:"transaction"`
+	// Block is a block tag, number or hash, or a {"blockHash", "requireCanonical"}
+	// object. It is "latest" when not set
+	Block *ethtypes.EthBlockNumberOrHash `json:"block"`
+}
+
+// ethGetLogsParams are the parameters of an EthGetLogs call
+type ethGetLogsParams struct {
+	Filter ethtypes.EthFilterSpec `json:"filter"`
+}
+
+// EthGetTransactionReceipt forwards eth_getTransactionReceipt
+func (s *CallAPIService) EthGetTransactionReceipt(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params ethHashParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	var (
+		receipt *ethtypes.EthTxReceipt
+		err     error
+	)
+	impl := func() {
+		receipt, err = s.node.EthGetTransactionReceipt(ctx, params.Hash)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, ethError(err)
+	}
+
+	if receipt == nil {
+		return nil, rosetta.BuildError(services.ErrMessageNotFound, nil, true)
+	}
+
+	return ethResponse(receipt)
+}
+
+// EthGetTransactionByHash forwards eth_getTransactionByHash
+func (s *CallAPIService) EthGetTransactionByHash(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params ethHashParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	var (
+		tx  *ethtypes.EthTx
+		err error
+	)
+	impl := func() {
+		tx, err = s.node.EthGetTransactionByHash(ctx, &params.Hash)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, ethError(err)
+	}
+
+	if tx == nil {
+		return nil, rosetta.BuildError(services.ErrMessageNotFound, nil, true)
+	}
+
+	return ethResponse(tx)
+}
+
+// EthCall forwards eth_call
+func (s *CallAPIService) EthCall(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params ethCallParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	block := ethtypes.NewEthBlockNumberOrHashFromPredefined(ethtypes.BlockTagLatest)
+	if params.Block != nil {
+		block = *params.Block
+	}
+
+	var (
+		data ethtypes.EthBytes
+		err  error
+	)
+	impl := func() {
+		data, err = s.node.EthCall(ctx, params.Transaction, block)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, ethError(err)
+	}
+
+	return ethResponse(map[string]interface{}{"data": data})
+}
+
+// EthGetLogs forwards eth_getLogs
+func (s *CallAPIService) EthGetLogs(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params ethGetLogsParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	var (
+		logs *ethtypes.EthFilterResult
+		err  error
+	)
+	impl := func() {
+		logs, err = s.node.EthGetLogs(ctx, &params.Filter)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, ethError(err)
+	}
+
+	results := []interface{}{}
+	if logs != nil && logs.Results != nil {
+		results = logs.Results
+	}
+
+	return ethResponse(map[string]interface{}{"logs": results})
+}
+
+// ethResponse normalizes a Lotus Ethereum API value to the JSON object
+// returned by eth_* methods
+func ethResponse(value interface{}) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+	var result map[string]interface{}
+	raw, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(raw, &result)
+	}
+	if err != nil {
+		return nil, rosetta.BuildError(services.ErrUnableToQueryEth, err, true)
+	}
+
+	return &rosettaTypes.CallResponse{
+		Result:     result,
+		Idempotent: false,
+	}, nil
+}
+
+// ethError maps the errors of the Lotus Ethereum API
+func ethError(err error) *rosettaTypes.Error {
+	var reverted *api.ErrExecutionReverted
+	if errors.As(err, &reverted) {
+		details := map[string]interface{}{
+			RevertDataKey: reverted.Data,
+		}
+		if data, decodeErr := hex.DecodeString(strings.TrimPrefix(reverted.Data, "0x")); decodeErr == nil {
+			if reason, ok := services.DecodeRevertReason(data); ok {
+				details[RevertReasonKey] = reason
+			}
+		}
+
+		revertErr := *services.ErrExecutionReverted
+		revertErr.Description = rosettaTypes.String(reverted.Message)
+		revertErr.Details = details
+		return &revertErr
+	}
+
+	var nullRound *api.ErrNullRound
+	if errors.As(err, &nullRound) {
+		return rosetta.BuildError(rosetta.ErrUnableToGetTipset, err, false)
+	}
+
+	return rosetta.BuildError(services.ErrUnableToQueryEth, err, true)
+}
@@ -0,0 +1,106 @@
+package call_test
+
+import (
+	"context"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+)
+
+const testEthHash = "0x8e0fd6cf4a1a2b4d2a9f4f9a0e1d5a6f7b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e"
+
+func TestEthCallReverted(t *testing.T) {
+	revert := []byte{0x08, 0xc3, 0x79, 0xa0}
+	revert = append(revert, make([]byte, 31)...)
+	revert = append(revert, 0x20)
+	revert = append(revert, make([]byte, 31)...)
+	revert = append(revert, 0x04)
+	revert = append(revert, []byte("nope")...)
+	revert = append(revert, make([]byte, 28)...)
+
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("EthCall", mock.Anything, mock.Anything, ethtypes.NewEthBlockNumberOrHashFromPredefined(ethtypes.BlockTagLatest)).
+		Return(nil, api.NewErrExecutionReverted(exitcode.ExitCode(33), "", "nope", revert))
+
+	_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.EthCallCall,
+		Parameters: map[string]interface{}{
+			"transaction": map[string]interface{}{
+				"to":   "0xd4c5fb16488aa48081296299d54b0c648c9333da",
+				"data": "0x70a08231",
+			},
+		},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrExecutionReverted.Code, rosettaErr.Code)
+	assert.Equal(t, "nope", rosettaErr.Details[call.RevertReasonKey])
+}
+
+func TestEthCallBlockObject(t *testing.T) {
+	hash, err := ethtypes.ParseEthHash(testEthHash)
+	require.NoError(t, err)
+
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("EthCall", mock.Anything, mock.Anything, mock.MatchedBy(func(block ethtypes.EthBlockNumberOrHash) bool {
+		return block.BlockHash != nil && *block.BlockHash == hash && block.RequireCanonical
+	})).Return(ethtypes.EthBytes{0x01}, nil)
+
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.EthCallCall,
+		Parameters: map[string]interface{}{
+			"transaction": map[string]interface{}{
+				"to":   "0xd4c5fb16488aa48081296299d54b0c648c9333da",
+				"data": "0x70a08231",
+			},
+			"block": map[string]interface{}{
+				"blockHash":        testEthHash,
+				"requireCanonical": true,
+			},
+		},
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, "0x01", resp.Result["data"])
+}
+
+func TestEthGetLogs(t *testing.T) {
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("EthGetLogs", mock.Anything, mock.MatchedBy(func(filter *ethtypes.EthFilterSpec) bool {
+		return filter.FromBlock != nil && *filter.FromBlock == "0x10"
+	})).Return(&ethtypes.EthFilterResult{}, nil)
+
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.EthGetLogsCall,
+		Parameters: map[string]interface{}{
+			"filter": map[string]interface{}{"fromBlock": "0x10", "toBlock": "0x10"},
+		},
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, []interface{}{}, resp.Result["logs"])
+}
+
+func TestEthGetTransactionReceiptNotFound(t *testing.T) {
+	hash, err := ethtypes.ParseEthHash(testEthHash)
+	require.NoError(t, err)
+
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("EthGetTransactionReceipt", mock.Anything, hash).Return(nil, nil)
+
+	_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.EthGetTransactionReceiptCall,
+		Parameters:        map[string]interface{}{"hash": testEthHash},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrMessageNotFound.Code, rosettaErr.Code)
+}
@@ -0,0 +1,28 @@
+package call_test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tests/fixtures"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tests/mocks"
+)
+
+const (
+	testMessageCid       = fixtures.MessageCid
+	testGenesisTimestamp = fixtures.GenesisTimestamp
+	testBlockDelay       = fixtures.BlockDelay
+)
+
+var (
+	testNetwork = fixtures.Network
+	testTipSet  = fixtures.TipSet
+)
+
+func newCallService(t *testing.T) (*call.CallAPIService, *mocks.FullNode) {
+	fullNodeMock := fixtures.NewFullNode(t)
+
+	var node api.FullNode = fullNodeMock
+	return call.NewCallAPIService(testNetwork, &node, nil, nil).(*call.CallAPIService), fullNodeMock
+}
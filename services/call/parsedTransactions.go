@@ -0,0 +1,119 @@
+package call
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	filparser "github.com/zondax/fil-parser"
+	parserTypes "github.com/zondax/fil-parser/types"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+const ParsedTransactionsCall = "ParsedTransactions"
+
+var parsedTransactionsMethod = Method{
+	Name: ParsedTransactionsCall,
+	Params: []Param{
+		{Name: "index", Type: ParamNumber, Required: true},
+		{Name: "hash", Type: ParamString},
+	},
+	Handler: (*CallAPIService).ParsedTransactions,
+}
+
+// ParsedTransactions returns the transactions and addresses parsed by fil-parser
+// from a tipset, the same way /block does, in the fil-parser JSON schema
+func (s *CallAPIService) ParsedTransactions(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params rosettaTypes.PartialBlockIdentifier
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	tipSet, errTipSet := s.resolveTipSet(ctx, &params)
+	if errTipSet != nil {
+		return nil, errTipSet
+	}
+
+	transactions := []*parserTypes.Transaction{}
+	addresses := map[string]*parserTypes.AddressInfo{}
+
+	// Like /block, the first tipsets have no traces to parse
+	if tipSet.Height() > 1 {
+		states, errCompute := s.traceRetriever.GetStateCompute(ctx, &s.node, tipSet)
+		if errCompute != nil {
+			return nil, errCompute
+		}
+
+		parsed, errParse := s.parseTraces(ctx, tipSet, states.Trace)
+		if errParse != nil {
+			return nil, errParse
+		}
+		if parsed != nil {
+			if parsed.Txs != nil {
+				transactions = parsed.Txs
+			}
+			if parsed.Addresses != nil {
+				addresses = parsed.Addresses.Copy()
+			}
+		}
+	}
+
+	var result map[string]interface{}
+	raw, err := json.Marshal(map[string]interface{}{
+		"transactions": transactions,
+		"addresses":    addresses,
+	})
+	if err == nil {
+		err = json.Unmarshal(raw, &result)
+	}
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, err, true)
+	}
+
+	return &rosettaTypes.CallResponse{
+		Result:     result,
+		Idempotent: true,
+	}, nil
+}
+
+// parseTraces runs traces executed by a tipset through fil-parser
+func (s *CallAPIService) parseTraces(ctx context.Context, tipSet *filTypes.TipSet,
+	traces []*api.InvocResult) (*filparser.TxsParsedResult, *rosettaTypes.Error) {
+	if s.p == nil {
+		if s.parserErr != nil {
+			return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, fmt.Errorf("unable to create the parser: %w", s.parserErr), false)
+		}
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, fmt.Errorf("no parser available"), false)
+	}
+
+	tracesBytes, err := json.Marshal(traces)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, err, true)
+	}
+
+	extendedTipset := &parserTypes.ExtendedTipSet{}
+	tipsetBytes, err := json.Marshal(tipSet)
+	if err == nil {
+		err = extendedTipset.UnmarshalJSON(tipsetBytes)
+	}
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTipset, err, true)
+	}
+
+	parsed, err := s.p.ParseTransactions(ctx, parserTypes.TxsData{
+		Traces: tracesBytes,
+		Tipset: extendedTipset,
+	})
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, err, true)
+	}
+
+	return parsed, nil
+}
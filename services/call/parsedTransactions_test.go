@@ -0,0 +1,45 @@
+package call_test
+
+import (
+	"context"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+func TestParsedTransactions(t *testing.T) {
+	t.Run("first tipsets have no traces", func(t *testing.T) {
+		ts := testTipSet(t, 1)
+		s, fullNodeMock := newCallService(t)
+		fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, ts.Height(), filTypes.EmptyTSK).Return(ts, nil)
+
+		resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+			NetworkIdentifier: testNetwork,
+			Method:            call.ParsedTransactionsCall,
+			Parameters:        map[string]interface{}{"index": 1},
+		})
+		require.Nil(t, rosettaErr)
+		assert.Equal(t, []interface{}{}, resp.Result["transactions"])
+		assert.Equal(t, map[string]interface{}{}, resp.Result["addresses"])
+	})
+
+	t.Run("no parser", func(t *testing.T) {
+		ts := testTipSet(t, 100)
+		s := newStateComputeService(t, ts, []*api.InvocResult{})
+
+		_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+			NetworkIdentifier: testNetwork,
+			Method:            call.ParsedTransactionsCall,
+			Parameters:        map[string]interface{}{"index": 100},
+		})
+		require.NotNil(t, rosettaErr)
+		assert.Equal(t, rosetta.ErrUnableToGetTrace.Code, rosettaErr.Code)
+	})
+}
@@ -0,0 +1,188 @@
+package call
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+// ParamType is the JSON type of a call method parameter
+type ParamType string
+
+const (
+	ParamString ParamType = "string"
+	ParamNumber ParamType = "number"
+	ParamBool   ParamType = "bool"
+	ParamObject ParamType = "object"
+	// ParamStringOrObject accepts both a string and an object
+	ParamStringOrObject ParamType = "string or object"
+)
+
+// InvalidParamFieldKey is the name of the key in the Details map of an
+// ErrInvalidCallParameters error that specifies the rejected parameter
+const InvalidParamFieldKey = "field"
+
+// Param describes a parameter of a call method
+type Param struct {
+	Name     string
+	Type     ParamType
+	Required bool
+}
+
+// Handler serves a call method
+type Handler func(s *CallAPIService, ctx context.Context, request *rosettaTypes.CallRequest) (*rosettaTypes.CallResponse, *rosettaTypes.Error)
+
+// Method describes a method served by the /call endpoint
+type Method struct {
+	Name    string
+	Params  []Param
+	Handler Handler
+}
+
+// Registry holds the methods served by the /call endpoint
+type Registry struct {
+	methods map[string]Method
+	names   []string
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{
+		methods: make(map[string]Method),
+	}
+}
+
+// Register adds a method to the registry, names must be unique
+func (r *Registry) Register(method Method) error {
+	if method.Name == "" || method.Handler == nil {
+		return fmt.Errorf("call method needs a name and a handler")
+	}
+	if _, ok := r.methods[method.Name]; ok {
+		return fmt.Errorf("call method %s is already registered", method.Name)
+	}
+
+	r.methods[method.Name] = method
+	r.names = append(r.names, method.Name)
+	return nil
+}
+
+// MustRegister is like Register but panics on error
+func (r *Registry) MustRegister(method Method) {
+	if err := r.Register(method); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns the method registered with name
+func (r *Registry) Get(name string) (Method, bool) {
+	method, ok := r.methods[name]
+	return method, ok
+}
+
+// Names returns the names of the registered methods, in registration order.
+// They feed the asserter and /network/options.
+func (r *Registry) Names() []string {
+	return append([]string{}, r.names...)
+}
+
+// Methods is the registry of the methods served by CallAPIService
+var Methods = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.MustRegister(stateComputeMethod)
+	r.MustRegister(waitForMessageMethod)
+	r.MustRegister(replaceByFeeMethod)
+	r.MustRegister(stateCallMethod)
+	r.MustRegister(transactionStatusMethod)
+	r.MustRegister(actorStateMethod)
+	r.MustRegister(convertAddressMethod)
+	r.MustRegister(storageProviderInfoMethod)
+	r.MustRegister(ethGetTransactionReceiptMethod)
+	r.MustRegister(ethGetTransactionByHashMethod)
+	r.MustRegister(ethCallMethod)
+	r.MustRegister(ethGetLogsMethod)
+	r.MustRegister(estimateGasMethod)
+	r.MustRegister(tipSetByTimestampMethod)
+	r.MustRegister(replayMessageMethod)
+	r.MustRegister(parsedTransactionsMethod)
+	return r
+}
+
+// validateParams checks the request parameters against the method schema
+func (m *Method) validateParams(params map[string]interface{}) *rosettaTypes.Error {
+	for _, param := range m.Params {
+		value, ok := params[param.Name]
+		if !ok || value == nil {
+			if param.Required {
+				return invalidParam(param.Name, fmt.Errorf("missing required parameter"))
+			}
+			continue
+		}
+
+		if !param.Type.matches(value) {
+			return invalidParam(param.Name, fmt.Errorf("expected a %s, got %T", param.Type, value))
+		}
+	}
+
+	return nil
+}
+
+func (t ParamType) matches(value interface{}) bool {
+	switch value.(type) {
+	case string:
+		return t == ParamString || t == ParamStringOrObject
+	case float64, json.Number, int, int64, uint64:
+		return t == ParamNumber
+	case bool:
+		return t == ParamBool
+	case map[string]interface{}:
+		return t == ParamObject || t == ParamStringOrObject
+	default:
+		return false
+	}
+}
+
+// invalidParam returns an ErrInvalidCallParameters naming the rejected parameter
+func invalidParam(field string, reason error) *rosettaTypes.Error {
+	paramErr := *services.ErrInvalidCallParameters
+	paramErr.Description = rosettaTypes.String(fmt.Sprintf("invalid parameter %s: %v", field, reason))
+	paramErr.Details = map[string]interface{}{
+		InvalidParamFieldKey: field,
+	}
+	return &paramErr
+}
+
+// decodeParams decodes the parameters of a call request into params
+func decodeParams(request *rosettaTypes.CallRequest, params interface{}) *rosettaTypes.Error {
+	raw, err := json.Marshal(request.Parameters)
+	if err != nil {
+		return rosetta.BuildError(rosetta.ErrMalformedValue, err, false)
+	}
+
+	err = json.Unmarshal(raw, params)
+	if err != nil {
+		return rosetta.BuildError(rosetta.ErrMalformedValue, err, false)
+	}
+
+	return nil
+}
+
+// dispatch runs the registered handler of a call request
+func (s *CallAPIService) dispatch(ctx context.Context, request *rosettaTypes.CallRequest) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+	method, ok := Methods.Get(request.Method)
+	if !ok {
+		return nil, rosetta.BuildError(rosetta.ErrOperationNotSupported, nil, true)
+	}
+
+	errParams := method.validateParams(request.Parameters)
+	if errParams != nil {
+		return nil, errParams
+	}
+
+	return method.Handler(s, ctx, request)
+}
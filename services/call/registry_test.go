@@ -0,0 +1,57 @@
+package call_test
+
+import (
+	"context"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+)
+
+func TestRegistry(t *testing.T) {
+	handler := func(*call.CallAPIService, context.Context, *rosettaTypes.CallRequest) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+		return &rosettaTypes.CallResponse{}, nil
+	}
+
+	r := call.NewRegistry()
+	require.NoError(t, r.Register(call.Method{Name: "First", Handler: handler}))
+	require.NoError(t, r.Register(call.Method{Name: "Second", Handler: handler}))
+	assert.Error(t, r.Register(call.Method{Name: "First", Handler: handler}))
+	assert.Error(t, r.Register(call.Method{Name: "NoHandler"}))
+
+	assert.Equal(t, []string{"First", "Second"}, r.Names())
+	_, ok := r.Get("Second")
+	assert.True(t, ok)
+	_, ok = r.Get("Unknown")
+	assert.False(t, ok)
+}
+
+func TestCallValidatesParameters(t *testing.T) {
+	assert.Contains(t, call.Methods.Names(), call.WaitForMessageCall)
+
+	s, _ := newCallService(t)
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		field  string
+	}{
+		{name: "missing required", params: map[string]interface{}{}, field: "cid"},
+		{name: "wrong type", params: map[string]interface{}{"cid": testMessageCid, "confidence": "two"}, field: "confidence"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+				NetworkIdentifier: testNetwork,
+				Method:            call.WaitForMessageCall,
+				Parameters:        tt.params,
+			})
+			require.NotNil(t, rosettaErr)
+			assert.Equal(t, services.ErrInvalidCallParameters.Code, rosettaErr.Code)
+			assert.Equal(t, tt.field, rosettaErr.Details[call.InvalidParamFieldKey])
+		})
+	}
+}
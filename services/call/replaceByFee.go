@@ -0,0 +1,164 @@
+package call
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const ReplaceByFeeCall = "ReplaceByFee"
+
+var replaceByFeeMethod = Method{
+	Name: ReplaceByFeeCall,
+	Params: []Param{
+		{Name: "cid", Type: ParamString, Required: true},
+		{Name: "maxFee", Type: ParamString},
+	},
+	Handler: (*CallAPIService).ReplaceByFee,
+}
+
+const (
+	// rbfMinimumPercentage is the minimum premium increase, in percent, the Lotus
+	// mempool accepts to replace a message (messagepool.ReplaceByFeePercentageMinimum)
+	rbfMinimumPercentage = 110
+
+	// MinGasPremiumKey is the name of the key in the result of a ReplaceByFee call
+	// that specifies the lowest premium the mempool accepts for the replacement
+	MinGasPremiumKey = "minGasPremium"
+
+	// UnsignedTransactionKey is the name of the key in the result of a ReplaceByFee
+	// call that specifies the replacement message, ready for /construction/combine
+	UnsignedTransactionKey = "unsignedTransaction"
+)
+
+// replaceByFeeParams are the parameters of a ReplaceByFee call
+type replaceByFeeParams struct {
+	Cid string `json:"cid"`
+	// MaxFee in attoFIL, optional
+	MaxFee string `json:"maxFee"`
+}
+
+// ReplaceByFee computes the gas values of a message replacing a pending one, with
+// the same nonce, following the rules of the Lotus mempool
+func (s *CallAPIService) ReplaceByFee(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params replaceByFeeParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	msgCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrInvalidHash, err, false)
+	}
+
+	maxFee := big.Zero()
+	if params.MaxFee != "" {
+		maxFee, err = filTypes.BigFromString(params.MaxFee)
+		if err != nil || maxFee.Sign() <= 0 {
+			return nil, rosetta.BuildError(rosetta.ErrMalformedValue, fmt.Errorf("maxFee must be a positive amount"), false)
+		}
+	}
+
+	var pending []*filTypes.SignedMessage
+	impl := func() {
+		pending, err = s.node.MpoolPending(ctx, filTypes.EmptyTSK)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, rosetta.BuildError(services.ErrUnableToGetMempool, err, true)
+	}
+
+	var original *filTypes.Message
+	for _, signedMsg := range pending {
+		if signedMsg.Cid() == msgCid || signedMsg.Message.Cid() == msgCid {
+			original = &signedMsg.Message
+			break
+		}
+	}
+
+	if original == nil {
+		return nil, rosetta.BuildError(services.ErrMessageNotPending, fmt.Errorf("message %s is not in the mempool", msgCid), false)
+	}
+
+	minPremium := minReplacementPremium(original.GasPremium)
+
+	// Estimate again as if it was a new message, keeping the gas limit, the same
+	// way `lotus mpool replace --auto` does
+	estimate := *original
+	estimate.GasFeeCap = abi.NewTokenAmount(0)
+	estimate.GasPremium = abi.NewTokenAmount(0)
+	var estimated *filTypes.Message
+	impl = func() {
+		estimated, err = s.node.GasEstimateMessageGas(ctx, &estimate, &api.MessageSendSpec{MaxFee: maxFee}, filTypes.EmptyTSK)
+	}
+
+	errTimeOut = rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToEstimateGasLimit, err, true)
+	}
+
+	replacement := *original
+	replacement.GasPremium = big.Max(estimated.GasPremium, minPremium)
+	replacement.GasFeeCap = big.Max(estimated.GasFeeCap, replacement.GasPremium)
+
+	if !maxFee.IsZero() {
+		maxFeeCap := big.Div(maxFee, big.NewInt(replacement.GasLimit))
+		if maxFeeCap.LessThan(minPremium) {
+			return nil, rosetta.BuildError(rosetta.ErrMalformedValue,
+				fmt.Errorf("maxFee is too low to replace the message, the premium must be at least %s", minPremium), false)
+		}
+		replacement.GasFeeCap = big.Min(replacement.GasFeeCap, maxFeeCap)
+		// The mempool rejects a premium above the fee cap, which is still at
+		// least the minimum premium
+		replacement.GasPremium = big.Min(replacement.GasPremium, replacement.GasFeeCap)
+	}
+
+	unsignedTx, err := json.Marshal(&replacement)
+	if err != nil {
+		return nil, rosetta.BuildError(services.ErrMalformedTransaction, err, false)
+	}
+
+	res := &rosettaTypes.CallResponse{
+		Result: map[string]interface{}{
+			services.NonceKey:      replacement.Nonce,
+			services.GasLimitKey:   replacement.GasLimit,
+			services.GasPremiumKey: replacement.GasPremium.String(),
+			services.GasFeeCapKey:  replacement.GasFeeCap.String(),
+			MinGasPremiumKey:       minPremium.String(),
+			UnsignedTransactionKey: string(unsignedTx),
+		},
+		Idempotent: false,
+	}
+
+	return res, nil
+}
+
+// minReplacementPremium returns the lowest premium the Lotus mempool accepts to
+// replace a message, see messagepool.ComputeMinRBF
+func minReplacementPremium(premium abi.TokenAmount) abi.TokenAmount {
+	minPremium := big.Div(big.Mul(premium, big.NewInt(rbfMinimumPercentage)), big.NewInt(100))
+	return big.Add(minPremium, big.NewInt(1))
+}
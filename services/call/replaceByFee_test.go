@@ -0,0 +1,121 @@
+package call_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+)
+
+func pendingMessage(t *testing.T) *filTypes.SignedMessage {
+	from, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	to, err := address.NewIDAddress(1002)
+	require.NoError(t, err)
+
+	return &filTypes.SignedMessage{
+		Message: filTypes.Message{
+			From:       from,
+			To:         to,
+			Nonce:      12,
+			Value:      abi.NewTokenAmount(10),
+			GasLimit:   1000,
+			GasPremium: abi.NewTokenAmount(1000),
+			GasFeeCap:  abi.NewTokenAmount(1500),
+		},
+		Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1, Data: []byte{1}},
+	}
+}
+
+func TestReplaceByFee(t *testing.T) {
+	pending := pendingMessage(t)
+
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("MpoolPending", mock.Anything, filTypes.EmptyTSK).Return([]*filTypes.SignedMessage{pending}, nil)
+	fullNodeMock.On("GasEstimateMessageGas", mock.Anything, mock.Anything, mock.Anything, filTypes.EmptyTSK).
+		Return(func(_ context.Context, msg *filTypes.Message, _ *api.MessageSendSpec, _ filTypes.TipSetKey) *filTypes.Message {
+			estimated := *msg
+			estimated.GasPremium = abi.NewTokenAmount(500)
+			estimated.GasFeeCap = abi.NewTokenAmount(2000)
+			return &estimated
+		}, nil)
+
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.ReplaceByFeeCall,
+		Parameters:        map[string]interface{}{"cid": pending.Cid().String()},
+	})
+	require.Nil(t, rosettaErr)
+
+	assert.Equal(t, uint64(12), resp.Result[services.NonceKey])
+	assert.Equal(t, int64(1000), resp.Result[services.GasLimitKey])
+	assert.Equal(t, "1101", resp.Result[call.MinGasPremiumKey])
+	assert.Equal(t, "1101", resp.Result[services.GasPremiumKey])
+	assert.Equal(t, "2000", resp.Result[services.GasFeeCapKey])
+	assert.NotEmpty(t, resp.Result[call.UnsignedTransactionKey])
+}
+
+func TestReplaceByFeeNotPending(t *testing.T) {
+	pending := pendingMessage(t)
+
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("MpoolPending", mock.Anything, filTypes.EmptyTSK).Return([]*filTypes.SignedMessage{}, nil)
+
+	_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.ReplaceByFeeCall,
+		Parameters:        map[string]interface{}{"cid": pending.Cid().String()},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrMessageNotPending.Code, rosettaErr.Code)
+}
+
+func TestReplaceByFeeMaxFeeCapsPremium(t *testing.T) {
+	pending := pendingMessage(t)
+
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("MpoolPending", mock.Anything, filTypes.EmptyTSK).Return([]*filTypes.SignedMessage{pending}, nil)
+	fullNodeMock.On("GasEstimateMessageGas", mock.Anything, mock.Anything, mock.Anything, filTypes.EmptyTSK).
+		Return(func(_ context.Context, msg *filTypes.Message, _ *api.MessageSendSpec, _ filTypes.TipSetKey) *filTypes.Message {
+			estimated := *msg
+			estimated.GasPremium = abi.NewTokenAmount(3000)
+			estimated.GasFeeCap = abi.NewTokenAmount(5000)
+			return &estimated
+		}, nil)
+
+	// 2000000 / 1000 gas limit caps the fee cap to 2000, below the estimated premium
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.ReplaceByFeeCall,
+		Parameters:        map[string]interface{}{"cid": pending.Cid().String(), "maxFee": "2000000"},
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, "2000", resp.Result[services.GasFeeCapKey])
+	assert.Equal(t, "2000", resp.Result[services.GasPremiumKey])
+}
+
+func TestReplaceByFeeMempoolError(t *testing.T) {
+	pending := pendingMessage(t)
+
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("MpoolPending", mock.Anything, filTypes.EmptyTSK).Return(nil, errors.New("mpool unavailable"))
+
+	_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.ReplaceByFeeCall,
+		Parameters:        map[string]interface{}{"cid": pending.Cid().String()},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrUnableToGetMempool.Code, rosettaErr.Code)
+}
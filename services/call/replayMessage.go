@@ -0,0 +1,176 @@
+package call
+
+import (
+	"context"
+	"encoding/json"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const ReplayMessageCall = "ReplayMessage"
+
+var replayMessageMethod = Method{
+	Name: ReplayMessageCall,
+	Params: []Param{
+		{Name: "cid", Type: ParamString, Required: true},
+		{Name: "blockIdentifier", Type: ParamObject},
+	},
+	Handler: (*CallAPIService).ReplayMessage,
+}
+
+// replayMessageParams are the parameters of a ReplayMessage call
+type replayMessageParams struct {
+	Cid string `json:"cid"`
+	// BlockIdentifier is the block including the message, looked up when not set
+	BlockIdentifier *rosettaTypes.PartialBlockIdentifier `json:"blockIdentifier"`
+}
+
+// ReplayMessage replays a single message of the chain and returns its execution
+// trace, with its subcalls and gas charges, and the transactions parsed from it
+func (s *CallAPIService) ReplayMessage(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params replayMessageParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	msgCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrInvalidHash, err, false)
+	}
+
+	var included *filTypes.TipSet
+	replayCid := msgCid
+	if params.BlockIdentifier != nil {
+		var errTipSet *rosettaTypes.Error
+		included, errTipSet = s.resolveTipSet(ctx, params.BlockIdentifier)
+		if errTipSet != nil {
+			return nil, errTipSet
+		}
+	} else {
+		var lookup *api.MsgLookup
+		impl := func() {
+			lookup, err = s.node.StateSearchMsg(ctx, filTypes.EmptyTSK, msgCid, api.LookbackNoLimit, true)
+			if err != nil || lookup == nil {
+				return
+			}
+			included, err = s.inclusionTipSet(ctx, lookup.TipSet)
+		}
+
+		errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+		if errTimeOut != nil {
+			return nil, rosetta.ErrLotusCallTimedOut
+		}
+		if err != nil {
+			return nil, rosetta.BuildError(services.ErrUnableToGetReceipt, err, true)
+		}
+		if lookup == nil {
+			return nil, rosetta.BuildError(services.ErrMessageNotFound, nil, true)
+		}
+		// The included message differs from the requested one when it was replaced
+		replayCid = lookup.Message
+	}
+
+	var result *api.InvocResult
+	impl := func() {
+		result, err = s.node.StateReplay(ctx, included.Key(), replayCid)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, err, true)
+	}
+
+	var executionTrace map[string]interface{}
+	raw, err := json.Marshal(result.ExecutionTrace)
+	if err == nil {
+		err = json.Unmarshal(raw, &executionTrace)
+	}
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, err, true)
+	}
+
+	transactions, errParse := s.parseReplay(ctx, included, result)
+	if errParse != nil {
+		return nil, errParse
+	}
+
+	blockId, errBlock := blockIdentifier(included)
+	if errBlock != nil {
+		return nil, errBlock
+	}
+
+	callResult := map[string]interface{}{
+		"message": result.MsgCid.String(),
+		"blockIdentifier": map[string]interface{}{
+			"index": blockId.Index,
+			"hash":  blockId.Hash,
+		},
+		"executionTrace": executionTrace,
+		"transactions":   transactions,
+	}
+	if result.MsgRct != nil {
+		callResult["exitCode"] = int64(result.MsgRct.ExitCode)
+		callResult["gasUsed"] = result.MsgRct.GasUsed
+	}
+	if result.Error != "" {
+		callResult["error"] = result.Error
+	}
+
+	return &rosettaTypes.CallResponse{
+		Result:     callResult,
+		Idempotent: true,
+	}, nil
+}
+
+// parseReplay runs the replayed message through fil-parser, which decodes the
+// params and returns of every call. The metadata holding them is returned as
+// JSON objects. Nothing is parsed when no rosetta lib was given, while a parser
+// that failed to be created is reported.
+func (s *CallAPIService) parseReplay(ctx context.Context, tipSet *filTypes.TipSet,
+	result *api.InvocResult) ([]interface{}, *rosettaTypes.Error) {
+	transactions := []interface{}{}
+	if s.p == nil && s.parserErr == nil {
+		return transactions, nil
+	}
+
+	parsed, errParse := s.parseTraces(ctx, tipSet, []*api.InvocResult{result})
+	if errParse != nil {
+		return nil, errParse
+	}
+	if parsed == nil {
+		return transactions, nil
+	}
+
+	for _, tx := range parsed.Txs {
+		var txMap map[string]interface{}
+		raw, err := json.Marshal(tx)
+		if err == nil {
+			err = json.Unmarshal(raw, &txMap)
+		}
+		if err != nil {
+			return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, err, true)
+		}
+
+		var metadata interface{}
+		if json.Unmarshal([]byte(tx.TxMetadata), &metadata) == nil {
+			txMap["tx_metadata"] = metadata
+		}
+		transactions = append(transactions, txMap)
+	}
+
+	return transactions, nil
+}
@@ -0,0 +1,86 @@
+package call_test
+
+import (
+	"context"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+)
+
+func TestReplayMessage(t *testing.T) {
+	msgCid, err := cid.Decode(testMessageCid)
+	require.NoError(t, err)
+
+	included := testTipSet(t, 100)
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, abi.ChainEpoch(100), filTypes.EmptyTSK).Return(included, nil)
+	fullNodeMock.On("StateReplay", mock.Anything, included.Key(), msgCid).Return(&api.InvocResult{
+		MsgCid: msgCid,
+		MsgRct: &filTypes.MessageReceipt{ExitCode: exitcode.Ok, GasUsed: 777},
+		ExecutionTrace: filTypes.ExecutionTrace{
+			GasCharges: []*filTypes.GasTrace{{Name: "OnChainMessage", TotalGas: 100}},
+			Subcalls: []filTypes.ExecutionTrace{
+				{Msg: filTypes.MessageTrace{Method: 2}},
+			},
+		},
+	}, nil)
+
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.ReplayMessageCall,
+		Parameters: map[string]interface{}{
+			"cid":             testMessageCid,
+			"blockIdentifier": map[string]interface{}{"index": 100},
+		},
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, int64(777), resp.Result["gasUsed"])
+	assert.Equal(t, int64(100), resp.Result["blockIdentifier"].(map[string]interface{})["index"])
+
+	trace := resp.Result["executionTrace"].(map[string]interface{})
+	assert.Len(t, trace["GasCharges"], 1)
+	assert.Len(t, trace["Subcalls"], 1)
+	assert.Empty(t, resp.Result["transactions"])
+}
+
+func TestReplayMessageReplaced(t *testing.T) {
+	msgCid, err := cid.Decode(testMessageCid)
+	require.NoError(t, err)
+	replacementCid, err := cid.Decode("bafy2bzacedlsjvanxqizrr7tkiw2kc7qj7frbyjjtmyaj4qyw7mwqyd6xsqqe")
+	require.NoError(t, err)
+
+	included := testTipSet(t, 100)
+	executed := testTipSet(t, 101)
+
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("StateSearchMsg", mock.Anything, filTypes.EmptyTSK, msgCid, api.LookbackNoLimit, true).Return(&api.MsgLookup{
+		Message: replacementCid,
+		Receipt: filTypes.MessageReceipt{ExitCode: exitcode.Ok, GasUsed: 777},
+		TipSet:  executed.Key(),
+		Height:  executed.Height(),
+	}, nil)
+	fullNodeMock.On("ChainGetTipSet", mock.Anything, executed.Key()).Return(executed, nil)
+	fullNodeMock.On("ChainGetTipSet", mock.Anything, executed.Parents()).Return(included, nil)
+	fullNodeMock.On("StateReplay", mock.Anything, included.Key(), replacementCid).Return(&api.InvocResult{
+		MsgCid: replacementCid,
+		MsgRct: &filTypes.MessageReceipt{ExitCode: exitcode.Ok, GasUsed: 777},
+	}, nil)
+
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.ReplayMessageCall,
+		Parameters:        map[string]interface{}{"cid": testMessageCid},
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, replacementCid.String(), resp.Result["message"])
+	assert.Equal(t, int64(100), resp.Result["blockIdentifier"].(map[string]interface{})["index"])
+}
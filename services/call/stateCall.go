@@ -0,0 +1,194 @@
+package call
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const StateCallCall = "StateCall"
+
+var stateCallMethod = Method{
+	Name: StateCallCall,
+	Params: []Param{
+		{Name: "from", Type: ParamString, Required: true},
+		{Name: "to", Type: ParamString, Required: true},
+		{Name: "value", Type: ParamString},
+		{Name: "method", Type: ParamNumber},
+		{Name: "params", Type: ParamString},
+		{Name: "blockIdentifier", Type: ParamObject},
+	},
+	Handler: (*CallAPIService).StateCall,
+}
+
+// messageParams are the parameters describing an unsigned message
+type messageParams struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Value in attoFIL, optional
+	Value  string `json:"value"`
+	Method uint64 `json:"method"`
+	// Params are 0x prefixed hex or base64 encoded
+	Params string `json:"params"`
+}
+
+// stateCallParams are the parameters of a StateCall call
+type stateCallParams struct {
+	messageParams
+	// BlockIdentifier defaults to the head tipset
+	BlockIdentifier *rosettaTypes.PartialBlockIdentifier `json:"blockIdentifier"`
+}
+
+// StateCall runs an unsigned message on top of a tipset without sending it and
+// returns its receipt, gas charges and execution trace
+func (s *CallAPIService) StateCall(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params stateCallParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	message, errMsg := params.message()
+	if errMsg != nil {
+		return nil, errMsg
+	}
+
+	tipSet, errTipSet := s.resolveTipSet(ctx, params.BlockIdentifier)
+	if errTipSet != nil {
+		return nil, errTipSet
+	}
+
+	var (
+		result *api.InvocResult
+		err    error
+	)
+	impl := func() {
+		result, err = s.node.StateCall(ctx, message, tipSet.Key())
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, rosetta.BuildError(services.ErrUnableToSimulateTx, err, true)
+	}
+
+	if result.MsgRct == nil {
+		return nil, rosetta.BuildError(services.ErrUnableToSimulateTx, fmt.Errorf("simulation returned no receipt"), true)
+	}
+
+	blockId, errBlock := blockIdentifier(tipSet)
+	if errBlock != nil {
+		return nil, errBlock
+	}
+
+	var executionTrace map[string]interface{}
+	raw, err := json.Marshal(result.ExecutionTrace)
+	if err == nil {
+		err = json.Unmarshal(raw, &executionTrace)
+	}
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, err, true)
+	}
+
+	gasCharges := make([]interface{}, 0, len(result.ExecutionTrace.GasCharges))
+	for _, charge := range result.ExecutionTrace.GasCharges {
+		gasCharges = append(gasCharges, map[string]interface{}{
+			"name":       charge.Name,
+			"totalGas":   charge.TotalGas,
+			"computeGas": charge.ComputeGas,
+			"storageGas": charge.StorageGas,
+		})
+	}
+
+	callResult := map[string]interface{}{
+		"blockIdentifier": map[string]interface{}{
+			"index": blockId.Index,
+			"hash":  blockId.Hash,
+		},
+		"exitCode":       int64(result.MsgRct.ExitCode),
+		"gasUsed":        result.MsgRct.GasUsed,
+		"gasCharges":     gasCharges,
+		"totalCost":      result.GasCost.TotalCost.String(),
+		"executionTrace": executionTrace,
+	}
+
+	if result.Error != "" {
+		callResult["error"] = result.Error
+	}
+
+	ret := services.DecodeReturn(result.MsgRct.Return)
+	if len(ret) > 0 {
+		callResult["return"] = "0x" + hex.EncodeToString(ret)
+		if reason, ok := services.DecodeRevertReason(ret); ok && !result.MsgRct.ExitCode.IsSuccess() {
+			callResult["revertReason"] = reason
+		}
+	}
+
+	return &rosettaTypes.CallResponse{
+		Result:     callResult,
+		Idempotent: params.BlockIdentifier != nil && params.BlockIdentifier.Index != nil,
+	}, nil
+}
+
+// message builds the message described by the call parameters
+func (p *messageParams) message() (*filTypes.Message, *rosettaTypes.Error) {
+	from, err := address.NewFromString(p.From)
+	if err != nil {
+		return nil, invalidParam("from", err)
+	}
+
+	to, err := address.NewFromString(p.To)
+	if err != nil {
+		return nil, invalidParam("to", err)
+	}
+
+	value := big.Zero()
+	if p.Value != "" {
+		value, err = filTypes.BigFromString(p.Value)
+		if err == nil && value.Sign() < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+		if err != nil {
+			return nil, invalidParam("value", err)
+		}
+	}
+
+	var msgParams []byte
+	if p.Params != "" {
+		if strings.HasPrefix(p.Params, "0x") {
+			msgParams, err = hex.DecodeString(p.Params[2:])
+		} else {
+			msgParams, err = base64.StdEncoding.DecodeString(p.Params)
+		}
+		if err != nil {
+			return nil, invalidParam("params", err)
+		}
+	}
+
+	return &filTypes.Message{
+		From:   from,
+		To:     to,
+		Value:  value,
+		Method: abi.MethodNum(p.Method),
+		Params: msgParams,
+	}, nil
+}
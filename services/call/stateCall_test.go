@@ -0,0 +1,79 @@
+package call_test
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+func TestStateCall(t *testing.T) {
+	// Error(string) reverting with "nope"
+	revert, err := hex.DecodeString("08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6e6f706500000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, err)
+
+	ts := testTipSet(t, 100)
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, abi.ChainEpoch(100), filTypes.EmptyTSK).Return(ts, nil)
+	fullNodeMock.On("StateCall", mock.Anything, mock.MatchedBy(func(msg *filTypes.Message) bool {
+		return msg.From.String() == "f01001" && msg.To.String() == "f01002" &&
+			msg.Method == 3844450837 && hex.EncodeToString(msg.Params) == "c0ffee"
+	}), ts.Key()).Return(&api.InvocResult{
+		MsgRct: &filTypes.MessageReceipt{ExitCode: exitcode.ExitCode(33), Return: revert, GasUsed: 4321},
+		ExecutionTrace: filTypes.ExecutionTrace{
+			GasCharges: []*filTypes.GasTrace{{Name: "OnChainMessage", TotalGas: 100, ComputeGas: 60, StorageGas: 40}},
+		},
+		GasCost: api.MsgGasCost{TotalCost: abi.NewTokenAmount(1000)},
+		Error:   "message execution failed",
+	}, nil)
+
+	index := int64(100)
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.StateCallCall,
+		Parameters: map[string]interface{}{
+			"from":            "f01001",
+			"to":              "f01002",
+			"method":          3844450837,
+			"params":          "0xc0ffee",
+			"blockIdentifier": map[string]interface{}{"index": index},
+		},
+	})
+	require.Nil(t, rosettaErr)
+	assert.True(t, resp.Idempotent)
+	assert.Equal(t, int64(33), resp.Result["exitCode"])
+	assert.Equal(t, int64(4321), resp.Result["gasUsed"])
+	assert.Equal(t, "nope", resp.Result["revertReason"])
+	assert.Equal(t, "1000", resp.Result["totalCost"])
+	assert.Len(t, resp.Result["gasCharges"], 1)
+}
+
+func TestStateCallNullRound(t *testing.T) {
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, abi.ChainEpoch(101), filTypes.EmptyTSK).Return(testTipSet(t, 100), nil)
+
+	_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.StateCallCall,
+		Parameters: map[string]interface{}{
+			"from":            "f01001",
+			"to":              "f01002",
+			"blockIdentifier": map[string]interface{}{"index": 101},
+		},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, rosetta.ErrUnableToGetTipset.Code, rosettaErr.Code)
+}
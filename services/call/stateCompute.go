@@ -3,75 +3,82 @@ package call
 import (
 	"context"
 	"encoding/json"
+
 	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
-	"github.com/filecoin-project/go-state-types/abi"
-	filTypes "github.com/filecoin-project/lotus/chain/types"
-	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
 	"github.com/zondax/filecoin-indexing-rosetta-proxy/tools"
 	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
-	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
 )
 
 const StateComputeCall = "StateCompute"
 
+var stateComputeMethod = Method{
+	Name: StateComputeCall,
+	Params: []Param{
+		{Name: "index", Type: ParamNumber},
+		{Name: "hash", Type: ParamString},
+		{Name: "cid", Type: ParamString},
+		{Name: "from", Type: ParamString},
+		{Name: "to", Type: ParamString},
+		{Name: "method", Type: ParamNumber},
+		{Name: "dropGasCharges", Type: ParamBool},
+		{Name: "maxDepth", Type: ParamNumber},
+	},
+	Handler: (*CallAPIService).StateComputeVersioned,
+}
+
+// stateComputeParams are the parameters of a StateCompute call, the index is
+// required and the hash, when set, must match the tipset found at the index
+type stateComputeParams struct {
+	rosettaTypes.PartialBlockIdentifier
+	stateComputeFilterParams
+}
+
+// StateComputeVersioned returns the traces of the messages executed by a
+// tipset, optionally filtered and trimmed
 func (s *CallAPIService) StateComputeVersioned(
 	ctx context.Context,
 	request *rosettaTypes.CallRequest,
 ) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
 
-	blockId := rosettaTypes.BlockIdentifier{}
-	params, err := json.Marshal(request.Parameters)
-	if err != nil {
-		rosetta.Logger.Errorf("Error on request.Parameters: %s", err.Error())
-		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, nil, true)
+	var params stateComputeParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		rosetta.Logger.Errorf("Error while unmarshaling parameters: %v", errParams.Details)
+		return nil, errParams
 	}
 
-	err = json.Unmarshal(params, &blockId)
-	if err != nil {
-		rosetta.Logger.Errorf("Error while unmarshaling parameters: %s", err.Error())
-		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, nil, true)
+	filter, errFilter := newStateComputeFilter(&params.stateComputeFilterParams)
+	if errFilter != nil {
+		return nil, errFilter
 	}
 
-	requestedHeight := blockId.Index
-	if requestedHeight < 0 {
-		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, nil, true)
+	// The hash can not be resolved to a tipset on its own
+	if params.Index == nil {
+		return nil, rosetta.BuildError(rosetta.ErrInsufficientQueryInputs, nil, false)
 	}
 
 	rosetta.Logger.Infof(tools.ConnectedToLotusVersion)
-	rosetta.Logger.Infof("/StateComputeVersioned - requested index %d", requestedHeight)
+	rosetta.Logger.Infof("/StateComputeVersioned - requested index %d", *params.Index)
 
-	var tipSet *filTypes.TipSet
-	impl := func() {
-		tipSet, err = s.node.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(requestedHeight), filTypes.EmptyTSK)
+	// Null rounds and mismatching hashes are reported as errors
+	tipSet, errTipSet := s.resolveTipSet(ctx, &params.PartialBlockIdentifier)
+	if errTipSet != nil {
+		return nil, errTipSet
 	}
 
-	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
-	if errTimeOut != nil {
-		return nil, rosetta.ErrLotusCallTimedOut
+	computeStateVersioned, errCompute := s.traceRetriever.GetStateCompute(ctx, &s.node, tipSet)
+	if errCompute != nil {
+		return nil, errCompute
 	}
 
-	if err != nil {
-		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTipset, err, true)
-	}
-
-	// If a TipSet has empty blocks, lotus api will return a TipSet at a different epoch
-	// Check if the retrieved TipSet is actually the requested one
-	// details on: https://github.com/filecoin-project/lotus/blob/49d64f7f7e22973ca0cfbaaf337fcfb3c2d47707/api/api_full.go#L65-L67
-	if int64(tipSet.Height()) != requestedHeight {
-		return nil, nil
-	}
-
-	computeStateVersioned, err2 := s.traceRetriever.GetStateCompute(ctx, &s.node, tipSet)
-
-	if err2 != nil {
-		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, err, true)
-	}
+	computeStateVersioned.Trace = filter.apply(computeStateVersioned.Trace)
 
 	var computeStateMap map[string]interface{}
-	m, _ := json.Marshal(computeStateVersioned)
-	err = json.Unmarshal(m, &computeStateMap)
+	m, err := json.Marshal(computeStateVersioned)
+	if err == nil {
+		err = json.Unmarshal(m, &computeStateMap)
+	}
 	if err != nil {
-		return nil, nil
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTrace, err, true)
 	}
 
 	res := &rosettaTypes.CallResponse{
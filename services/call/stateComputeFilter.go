@@ -0,0 +1,148 @@
+package call
+
+import (
+	"fmt"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+// stateComputeFilterParams are the optional parameters of a StateCompute call
+// selecting and trimming the returned traces
+type stateComputeFilterParams struct {
+	// Cid keeps the trace of a single message
+	Cid string `json:"cid"`
+	// From and To keep the traces of messages sent from or to an address, as it
+	// appears on the message
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Method keeps the traces of messages calling a method
+	Method *uint64 `json:"method"`
+	// DropGasCharges removes the gas charges of every call
+	DropGasCharges bool `json:"dropGasCharges"`
+	// MaxDepth removes the subcalls deeper than it, 0 keeps only the top level calls
+	MaxDepth *int64 `json:"maxDepth"`
+}
+
+// stateComputeFilter selects and trims the traces of a StateCompute call
+type stateComputeFilter struct {
+	cid            *cid.Cid
+	from           *address.Address
+	to             *address.Address
+	method         *abi.MethodNum
+	dropGasCharges bool
+	maxDepth       int64
+}
+
+// newStateComputeFilter validates the filter parameters
+func newStateComputeFilter(params *stateComputeFilterParams) (*stateComputeFilter, *rosettaTypes.Error) {
+	filter := &stateComputeFilter{
+		dropGasCharges: params.DropGasCharges,
+		maxDepth:       -1,
+	}
+
+	if params.Cid != "" {
+		msgCid, err := cid.Decode(params.Cid)
+		if err != nil {
+			return nil, rosetta.BuildError(rosetta.ErrInvalidHash, err, false)
+		}
+		filter.cid = &msgCid
+	}
+
+	if params.From != "" {
+		from, err := address.NewFromString(params.From)
+		if err != nil {
+			return nil, invalidParam("from", err)
+		}
+		filter.from = &from
+	}
+
+	if params.To != "" {
+		to, err := address.NewFromString(params.To)
+		if err != nil {
+			return nil, invalidParam("to", err)
+		}
+		filter.to = &to
+	}
+
+	if params.Method != nil {
+		method := abi.MethodNum(*params.Method)
+		filter.method = &method
+	}
+
+	if params.MaxDepth != nil {
+		if *params.MaxDepth < 0 {
+			return nil, invalidParam("maxDepth", fmt.Errorf("must not be negative"))
+		}
+		filter.maxDepth = *params.MaxDepth
+	}
+
+	return filter, nil
+}
+
+// apply returns the traces matching the filter, trimmed. The given traces are
+// left untouched.
+func (f *stateComputeFilter) apply(traces []*api.InvocResult) []*api.InvocResult {
+	filtered := make([]*api.InvocResult, 0, len(traces))
+	for _, trace := range traces {
+		if !f.matches(trace) {
+			continue
+		}
+
+		trimmed := *trace
+		trimmed.ExecutionTrace = f.trim(trace.ExecutionTrace, 0)
+		filtered = append(filtered, &trimmed)
+	}
+
+	return filtered
+}
+
+func (f *stateComputeFilter) matches(trace *api.InvocResult) bool {
+	if f.cid != nil && !trace.MsgCid.Equals(*f.cid) {
+		return false
+	}
+
+	if trace.Msg == nil {
+		return f.from == nil && f.to == nil && f.method == nil
+	}
+
+	if f.from != nil && trace.Msg.From != *f.from {
+		return false
+	}
+	if f.to != nil && trace.Msg.To != *f.to {
+		return false
+	}
+	if f.method != nil && trace.Msg.Method != *f.method {
+		return false
+	}
+
+	return true
+}
+
+// trim drops the gas charges and the subcalls below the maximum depth of a
+// trace found at depth
+func (f *stateComputeFilter) trim(trace filTypes.ExecutionTrace, depth int64) filTypes.ExecutionTrace {
+	if f.dropGasCharges {
+		trace.GasCharges = nil
+	}
+
+	if f.maxDepth >= 0 && depth >= f.maxDepth {
+		trace.Subcalls = nil
+		return trace
+	}
+
+	if len(trace.Subcalls) > 0 {
+		subcalls := make([]filTypes.ExecutionTrace, len(trace.Subcalls))
+		for i, subcall := range trace.Subcalls {
+			subcalls[i] = f.trim(subcall, depth+1)
+		}
+		trace.Subcalls = subcalls
+	}
+
+	return trace
+}
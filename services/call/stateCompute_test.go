@@ -0,0 +1,184 @@
+package call_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ds "github.com/Zondax/zindexer/components/connections/data_store"
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tests/fixtures"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tools"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+func newStateComputeService(t *testing.T, ts *filTypes.TipSet, traces []*api.InvocResult) *call.CallAPIService {
+	fullNodeMock := fixtures.NewFullNode(t)
+	fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, ts.Height(), filTypes.EmptyTSK).Return(ts, nil)
+	fullNodeMock.On("StateCompute", mock.Anything, ts.Height(), mock.Anything, ts.Key()).Return(&api.ComputeStateOutput{
+		Trace: traces,
+	}, nil)
+
+	var node api.FullNode = fullNodeMock
+	retriever := tools.NewTraceRetriever(false, "", ds.DataStoreConfig{Service: "local"})
+	return call.NewCallAPIService(testNetwork, &node, retriever, nil).(*call.CallAPIService)
+}
+
+func TestStateComputeFilter(t *testing.T) {
+	first, err := cid.Decode(testMessageCid)
+	require.NoError(t, err)
+	second, err := cid.Decode("bafy2bzacea3wsdh6y3a36tb3skempjoxqpuyompjbmfeyf34fi3uy6uue42v4")
+	require.NoError(t, err)
+	sender, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	receiver, err := address.NewIDAddress(1002)
+	require.NoError(t, err)
+
+	nested := filTypes.ExecutionTrace{
+		GasCharges: []*filTypes.GasTrace{{Name: "OnChainMessage"}},
+		Subcalls: []filTypes.ExecutionTrace{
+			{Subcalls: []filTypes.ExecutionTrace{{}}},
+		},
+	}
+	traces := []*api.InvocResult{
+		{MsgCid: first, Msg: &filTypes.Message{From: sender, To: receiver, Method: 2}, ExecutionTrace: nested},
+		{MsgCid: second, Msg: &filTypes.Message{From: receiver, To: sender}, ExecutionTrace: nested},
+	}
+
+	ts := testTipSet(t, 100)
+	tests := []struct {
+		name     string
+		params   map[string]interface{}
+		messages []string
+		check    func(t *testing.T, trace map[string]interface{})
+	}{
+		{
+			name:     "by sender",
+			params:   map[string]interface{}{"from": "f01001"},
+			messages: []string{first.String()},
+		},
+		{
+			name:     "by method",
+			params:   map[string]interface{}{"method": 0},
+			messages: []string{second.String()},
+		},
+		{
+			name:     "by cid and trimmed",
+			params:   map[string]interface{}{"cid": second.String(), "dropGasCharges": true, "maxDepth": 1},
+			messages: []string{second.String()},
+			check: func(t *testing.T, trace map[string]interface{}) {
+				assert.Nil(t, trace["GasCharges"])
+				subcalls := trace["Subcalls"].([]interface{})
+				require.Len(t, subcalls, 1)
+				assert.Nil(t, subcalls[0].(map[string]interface{})["Subcalls"])
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStateComputeService(t, ts, traces)
+
+			tt.params["index"] = int64(ts.Height())
+			resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+				NetworkIdentifier: testNetwork,
+				Method:            call.StateComputeCall,
+				Parameters:        tt.params,
+			})
+			require.Nil(t, rosettaErr)
+
+			results := resp.Result["Trace"].([]interface{})
+			var messages []string
+			for _, result := range results {
+				messages = append(messages, result.(map[string]interface{})["MsgCid"].(map[string]interface{})["/"].(string))
+			}
+			assert.Equal(t, tt.messages, messages)
+
+			if tt.check != nil {
+				tt.check(t, results[0].(map[string]interface{})["ExecutionTrace"].(map[string]interface{}))
+			}
+		})
+	}
+
+	// The unfiltered call keeps everything
+	s := newStateComputeService(t, ts, traces)
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.StateComputeCall,
+		Parameters:        map[string]interface{}{"index": 100},
+	})
+	require.Nil(t, rosettaErr)
+	assert.Len(t, resp.Result["Trace"], 2)
+}
+
+func TestStateComputeErrors(t *testing.T) {
+	ts := testTipSet(t, 100)
+
+	t.Run("hash mismatch", func(t *testing.T) {
+		fullNodeMock := fixtures.NewFullNode(t)
+		fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, ts.Height(), filTypes.EmptyTSK).Return(ts, nil)
+		var node api.FullNode = fullNodeMock
+		s := call.NewCallAPIService(testNetwork, &node, nil, nil)
+
+		_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+			NetworkIdentifier: testNetwork,
+			Method:            call.StateComputeCall,
+			Parameters:        map[string]interface{}{"index": 100, "hash": "not the hash"},
+		})
+		require.NotNil(t, rosettaErr)
+		assert.Equal(t, rosetta.ErrInvalidHash.Code, rosettaErr.Code)
+	})
+
+	t.Run("null round", func(t *testing.T) {
+		fullNodeMock := fixtures.NewFullNode(t)
+		fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, abi.ChainEpoch(101), filTypes.EmptyTSK).Return(ts, nil)
+		var node api.FullNode = fullNodeMock
+		s := call.NewCallAPIService(testNetwork, &node, nil, nil)
+
+		_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+			NetworkIdentifier: testNetwork,
+			Method:            call.StateComputeCall,
+			Parameters:        map[string]interface{}{"index": 101},
+		})
+		require.NotNil(t, rosettaErr)
+		assert.Equal(t, rosetta.ErrUnableToGetTipset.Code, rosettaErr.Code)
+	})
+
+	t.Run("missing index", func(t *testing.T) {
+		s, _ := newCallService(t)
+
+		_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+			NetworkIdentifier: testNetwork,
+			Method:            call.StateComputeCall,
+			Parameters:        map[string]interface{}{"hash": "bafy"},
+		})
+		require.NotNil(t, rosettaErr)
+		assert.Equal(t, rosetta.ErrInsufficientQueryInputs.Code, rosettaErr.Code)
+	})
+
+	t.Run("state compute failure", func(t *testing.T) {
+		fullNodeMock := fixtures.NewFullNode(t)
+		fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, ts.Height(), filTypes.EmptyTSK).Return(ts, nil)
+		fullNodeMock.On("StateCompute", mock.Anything, ts.Height(), mock.Anything, ts.Key()).Return(nil, errors.New("compute failed"))
+		var node api.FullNode = fullNodeMock
+		retriever := tools.NewTraceRetriever(false, "", ds.DataStoreConfig{Service: "local"})
+		s := call.NewCallAPIService(testNetwork, &node, retriever, nil)
+
+		_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+			NetworkIdentifier: testNetwork,
+			Method:            call.StateComputeCall,
+			Parameters:        map[string]interface{}{"index": 100},
+		})
+		require.NotNil(t, rosettaErr)
+		assert.Equal(t, rosetta.ErrUnableToGetTrace.Code, rosettaErr.Code)
+	})
+}
@@ -0,0 +1,133 @@
+package call
+
+import (
+	"context"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const StorageProviderInfoCall = "StorageProviderInfo"
+
+var storageProviderInfoMethod = Method{
+	Name: StorageProviderInfoCall,
+	Params: []Param{
+		{Name: "address", Type: ParamString, Required: true},
+		{Name: "blockIdentifier", Type: ParamObject},
+	},
+	Handler: (*CallAPIService).StorageProviderInfo,
+}
+
+// storageProviderInfoParams are the parameters of a StorageProviderInfo call
+type storageProviderInfoParams struct {
+	Address string `json:"address"`
+	// BlockIdentifier defaults to the head tipset
+	BlockIdentifier *rosettaTypes.PartialBlockIdentifier `json:"blockIdentifier"`
+}
+
+// StorageProviderInfo returns the addresses, power and locked funds of a storage provider
+func (s *CallAPIService) StorageProviderInfo(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params storageProviderInfoParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	minerAddr, err := address.NewFromString(params.Address)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrInvalidAccountAddress, err, false)
+	}
+
+	tipSet, errTipSet := s.resolveTipSet(ctx, params.BlockIdentifier)
+	if errTipSet != nil {
+		return nil, errTipSet
+	}
+
+	var (
+		info  api.MinerInfo
+		power *api.MinerPower
+		state *api.ActorState
+	)
+	impl := func() {
+		info, err = s.node.StateMinerInfo(ctx, minerAddr, tipSet.Key())
+		if err != nil {
+			return
+		}
+		power, err = s.node.StateMinerPower(ctx, minerAddr, tipSet.Key())
+		if err != nil {
+			return
+		}
+		state, err = s.node.StateReadState(ctx, minerAddr, tipSet.Key())
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, actorError(err)
+	}
+
+	locked, err := services.ReadMinerLockedFunds(state)
+	if err != nil {
+		return nil, rosetta.BuildError(services.ErrUnableToGetActor, err, true)
+	}
+
+	blockId, errBlock := blockIdentifier(tipSet)
+	if errBlock != nil {
+		return nil, errBlock
+	}
+
+	controlAddresses := make([]string, 0, len(info.ControlAddresses))
+	for _, control := range info.ControlAddresses {
+		controlAddresses = append(controlAddresses, control.String())
+	}
+
+	result := map[string]interface{}{
+		"blockIdentifier": map[string]interface{}{
+			"index": blockId.Index,
+			"hash":  blockId.Hash,
+		},
+		"owner":            info.Owner.String(),
+		"worker":           info.Worker.String(),
+		"controlAddresses": controlAddresses,
+		"beneficiary":      info.Beneficiary.String(),
+		"sectorSize":       uint64(info.SectorSize),
+		"power": map[string]interface{}{
+			"rawBytePower":    power.MinerPower.RawBytePower.String(),
+			"qualityAdjPower": power.MinerPower.QualityAdjPower.String(),
+			"hasMinPower":     power.HasMinPower,
+		},
+		"lockedFunds": map[string]interface{}{
+			"vesting":           locked.LockedFunds.String(),
+			"preCommitDeposits": locked.PreCommitDeposits.String(),
+			"initialPledge":     locked.InitialPledge.String(),
+			"feeDebt":           locked.FeeDebt.String(),
+		},
+		"balance": state.Balance.String(),
+	}
+
+	if info.BeneficiaryTerm != nil {
+		result["beneficiaryTerm"] = map[string]interface{}{
+			"quota":      info.BeneficiaryTerm.Quota.String(),
+			"usedQuota":  info.BeneficiaryTerm.UsedQuota.String(),
+			"expiration": int64(info.BeneficiaryTerm.Expiration),
+		}
+	}
+	if info.PeerId != nil {
+		result["peerId"] = info.PeerId.String()
+	}
+
+	return &rosettaTypes.CallResponse{
+		Result:     result,
+		Idempotent: params.BlockIdentifier != nil && params.BlockIdentifier.Index != nil,
+	}, nil
+}
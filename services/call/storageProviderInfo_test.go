@@ -0,0 +1,72 @@
+package call_test
+
+import (
+	"context"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/power"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+)
+
+func TestStorageProviderInfo(t *testing.T) {
+	minerAddr, err := address.NewIDAddress(1234)
+	require.NoError(t, err)
+	owner, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	worker, err := address.NewIDAddress(1002)
+	require.NoError(t, err)
+
+	ts := testTipSet(t, 100)
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, abi.ChainEpoch(100), mock.Anything).Return(ts, nil)
+	fullNodeMock.On("StateMinerInfo", mock.Anything, minerAddr, ts.Key()).Return(api.MinerInfo{
+		Owner:            owner,
+		Worker:           worker,
+		ControlAddresses: []address.Address{worker},
+		Beneficiary:      owner,
+		SectorSize:       abi.SectorSize(34359738368),
+	}, nil)
+	fullNodeMock.On("StateMinerPower", mock.Anything, minerAddr, ts.Key()).Return(&api.MinerPower{
+		MinerPower: power.Claim{
+			RawBytePower:    abi.NewStoragePower(1024),
+			QualityAdjPower: abi.NewStoragePower(10240),
+		},
+		HasMinPower: true,
+	}, nil)
+	fullNodeMock.On("StateReadState", mock.Anything, minerAddr, ts.Key()).Return(&api.ActorState{
+		Balance: abi.NewTokenAmount(1000),
+		State: map[string]interface{}{
+			"LockedFunds":       "300",
+			"PreCommitDeposits": "20",
+			"InitialPledge":     "500",
+			"FeeDebt":           "0",
+		},
+	}, nil)
+
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.StorageProviderInfoCall,
+		Parameters: map[string]interface{}{
+			"address":         "f01234",
+			"blockIdentifier": map[string]interface{}{"index": 100},
+		},
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, "f01001", resp.Result["owner"])
+	assert.Equal(t, []string{"f01002"}, resp.Result["controlAddresses"])
+	assert.Equal(t, uint64(34359738368), resp.Result["sectorSize"])
+	assert.Equal(t, "10240", resp.Result["power"].(map[string]interface{})["qualityAdjPower"])
+	assert.Equal(t, map[string]interface{}{
+		"vesting":           "300",
+		"preCommitDeposits": "20",
+		"initialPledge":     "500",
+		"feeDebt":           "0",
+	}, resp.Result["lockedFunds"])
+}
@@ -0,0 +1,146 @@
+package call
+
+import (
+	"context"
+	"fmt"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const TipSetByTimestampCall = "TipSetByTimestamp"
+
+var tipSetByTimestampMethod = Method{
+	Name: TipSetByTimestampCall,
+	Params: []Param{
+		{Name: "timestamp", Type: ParamNumber, Required: true},
+	},
+	Handler: (*CallAPIService).TipSetByTimestamp,
+}
+
+// tipSetByTimestampParams are the parameters of a TipSetByTimestamp call
+type tipSetByTimestampParams struct {
+	// Timestamp in UNIX seconds
+	Timestamp uint64 `json:"timestamp"`
+}
+
+// TipSetByTimestamp returns the block identifier of the last tipset produced at
+// or before a time
+func (s *CallAPIService) TipSetByTimestamp(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params tipSetByTimestampParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	var (
+		networkParams *api.NetworkParams
+		head          *filTypes.TipSet
+		tipSet        *filTypes.TipSet
+		err           error
+	)
+	impl := func() {
+		networkParams, err = s.node.StateGetNetworkParams(ctx)
+		if err != nil {
+			return
+		}
+		head, err = s.node.ChainHead(ctx)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTipset, err, true)
+	}
+
+	if params.Timestamp < networkParams.GenesisTimestamp {
+		return nil, invalidParam("timestamp", fmt.Errorf("before genesis at %d", networkParams.GenesisTimestamp))
+	}
+
+	if head.MinTimestamp() <= params.Timestamp {
+		tipSet = head
+	} else {
+		impl = func() {
+			tipSet, err = s.searchTipSetByTimestamp(ctx, head, params.Timestamp, networkParams)
+		}
+
+		errTimeOut = rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+		if errTimeOut != nil {
+			return nil, rosetta.ErrLotusCallTimedOut
+		}
+
+		if err != nil {
+			return nil, rosetta.BuildError(rosetta.ErrUnableToGetTipset, err, true)
+		}
+	}
+
+	blockId, errBlock := blockIdentifier(tipSet)
+	if errBlock != nil {
+		return nil, errBlock
+	}
+
+	return &rosettaTypes.CallResponse{
+		Result: map[string]interface{}{
+			"blockIdentifier": map[string]interface{}{
+				"index": blockId.Index,
+				"hash":  blockId.Hash,
+			},
+			"timestamp": tipSet.MinTimestamp(),
+		},
+		Idempotent: false,
+	}, nil
+}
+
+// searchTipSetByTimestamp binary searches the last tipset produced at or before
+// timestamp, which is older than head. For a null round Lotus returns the
+// previous tipset, so the timestamps found by height never decrease. Epochs
+// are produced every block delay since genesis, which bounds the search.
+func (s *CallAPIService) searchTipSetByTimestamp(ctx context.Context, head *filTypes.TipSet, timestamp uint64,
+	networkParams *api.NetworkParams) (*filTypes.TipSet, error) {
+	if networkParams.BlockDelaySecs == 0 {
+		return nil, fmt.Errorf("network block delay is zero")
+	}
+
+	high := abi.ChainEpoch((timestamp - networkParams.GenesisTimestamp) / networkParams.BlockDelaySecs)
+	if high >= head.Height() {
+		high = head.Height() - 1
+	}
+
+	var (
+		found *filTypes.TipSet
+		low   abi.ChainEpoch
+	)
+	// The bound is exact unless the clock drifted, so it is tried first
+	middle := high
+	for low <= high {
+
+		tipSet, err := s.node.ChainGetTipSetByHeight(ctx, middle, head.Key())
+		if err != nil {
+			return nil, err
+		}
+
+		if tipSet.MinTimestamp() <= timestamp {
+			found = tipSet
+			low = middle + 1
+		} else {
+			high = middle - 1
+		}
+		middle = low + (high-low)/2
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("no tipset at or before %d", timestamp)
+	}
+	return found, nil
+}
@@ -0,0 +1,76 @@
+package call_test
+
+import (
+	"context"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+)
+
+func TestTipSetByTimestamp(t *testing.T) {
+	head := testTipSet(t, 100)
+	nullRounds := map[abi.ChainEpoch]bool{40: true, 41: true}
+
+	tests := []struct {
+		name      string
+		timestamp uint64
+		index     int64
+	}{
+		{name: "exact epoch", timestamp: testGenesisTimestamp + 20*testBlockDelay, index: 20},
+		{name: "between epochs", timestamp: testGenesisTimestamp + 20*testBlockDelay + 29, index: 20},
+		{name: "null rounds", timestamp: testGenesisTimestamp + 41*testBlockDelay + 10, index: 39},
+		{name: "after head", timestamp: testGenesisTimestamp + 500*testBlockDelay, index: 100},
+		{name: "genesis", timestamp: testGenesisTimestamp, index: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, fullNodeMock := newCallService(t)
+			fullNodeMock.On("StateGetNetworkParams", mock.Anything).Return(&api.NetworkParams{
+				GenesisTimestamp: testGenesisTimestamp,
+				BlockDelaySecs:   testBlockDelay,
+			}, nil)
+			fullNodeMock.On("ChainHead", mock.Anything).Return(head, nil)
+			fullNodeMock.On("ChainGetTipSetByHeight", mock.Anything, mock.Anything, head.Key()).
+				Return(func(_ context.Context, height abi.ChainEpoch, _ filTypes.TipSetKey) *filTypes.TipSet {
+					for nullRounds[height] {
+						height--
+					}
+					return testTipSet(t, height)
+				}, nil).Maybe()
+
+			resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+				NetworkIdentifier: testNetwork,
+				Method:            call.TipSetByTimestampCall,
+				Parameters:        map[string]interface{}{"timestamp": tt.timestamp},
+			})
+			require.Nil(t, rosettaErr)
+			assert.Equal(t, tt.index, resp.Result["blockIdentifier"].(map[string]interface{})["index"])
+		})
+	}
+}
+
+func TestTipSetByTimestampBeforeGenesis(t *testing.T) {
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("StateGetNetworkParams", mock.Anything).Return(&api.NetworkParams{
+		GenesisTimestamp: testGenesisTimestamp,
+		BlockDelaySecs:   testBlockDelay,
+	}, nil)
+	fullNodeMock.On("ChainHead", mock.Anything).Return(testTipSet(t, 100), nil)
+
+	_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.TipSetByTimestampCall,
+		Parameters:        map[string]interface{}{"timestamp": testGenesisTimestamp - 1},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrInvalidCallParameters.Code, rosettaErr.Code)
+}
@@ -0,0 +1,30 @@
+package call
+
+import (
+	"context"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+// resolveTipSet returns the tipset of a block identifier, or the head tipset
+// when it is nil
+func (s *CallAPIService) resolveTipSet(ctx context.Context,
+	blockId *rosettaTypes.PartialBlockIdentifier) (*filTypes.TipSet, *rosettaTypes.Error) {
+	return services.ResolveTipSet(ctx, s.node, blockId)
+}
+
+// blockIdentifier returns the Rosetta block identifier of a tipset
+func blockIdentifier(tipSet *filTypes.TipSet) (*rosettaTypes.BlockIdentifier, *rosettaTypes.Error) {
+	tipSetKeyHash, err := rosetta.BuildTipSetKeyHash(tipSet.Key())
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToBuildTipSetHash, err, true)
+	}
+
+	return &rosettaTypes.BlockIdentifier{
+		Index: int64(tipSet.Height()),
+		Hash:  *tipSetKeyHash,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package call
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const TransactionStatusCall = "TransactionStatus"
+
+var transactionStatusMethod = Method{
+	Name: TransactionStatusCall,
+	Params: []Param{
+		{Name: "cid", Type: ParamString, Required: true},
+		{Name: "lookback", Type: ParamNumber},
+	},
+	Handler: (*CallAPIService).TransactionStatus,
+}
+
+// transactionStatusParams are the parameters of a TransactionStatus call
+type transactionStatusParams struct {
+	Cid string `json:"cid"`
+	// Lookback is the number of epochs searched back from the head, unlimited when not set
+	Lookback *int64 `json:"lookback"`
+}
+
+// TransactionStatus looks up where a message was included and returns the block
+// identifier of its tipset, its receipt and its operations
+func (s *CallAPIService) TransactionStatus(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params transactionStatusParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	msgCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrInvalidHash, err, false)
+	}
+
+	lookback := api.LookbackNoLimit
+	if params.Lookback != nil {
+		if *params.Lookback < 0 {
+			return nil, invalidParam("lookback", fmt.Errorf("must not be negative"))
+		}
+		lookback = abi.ChainEpoch(*params.Lookback)
+	}
+
+	var (
+		lookup   *api.MsgLookup
+		message  *filTypes.Message
+		included *filTypes.TipSet
+	)
+	impl := func() {
+		lookup, err = s.node.StateSearchMsg(ctx, filTypes.EmptyTSK, msgCid, lookback, true)
+		if err != nil || lookup == nil {
+			return
+		}
+		message, err = s.node.ChainGetMessage(ctx, lookup.Message)
+		if err != nil {
+			return
+		}
+		included, err = s.inclusionTipSet(ctx, lookup.TipSet)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, rosetta.BuildError(services.ErrUnableToGetReceipt, err, true)
+	}
+
+	if lookup == nil {
+		return nil, rosetta.BuildError(services.ErrMessageNotFound, nil, true)
+	}
+
+	blockId, errBlock := blockIdentifier(included)
+	if errBlock != nil {
+		return nil, errBlock
+	}
+
+	status := services.OperationStatusOk
+	if !lookup.Receipt.ExitCode.IsSuccess() {
+		status = services.OperationStatusFailed
+	}
+
+	// Methods the construction API does not know are reported without operations
+	operations, errOps := services.OperationsFromMessage(message)
+	if errOps != nil {
+		operations = []*rosettaTypes.Operation{}
+	}
+	for _, op := range operations {
+		op.Status = rosettaTypes.String(status)
+	}
+
+	res := &rosettaTypes.CallResponse{
+		Result: map[string]interface{}{
+			// The CID differs from the requested one when the message was replaced
+			"message": lookup.Message.String(),
+			"blockIdentifier": map[string]interface{}{
+				"index": blockId.Index,
+				"hash":  blockId.Hash,
+			},
+			"receipt": map[string]interface{}{
+				"exitCode": int64(lookup.Receipt.ExitCode),
+				"gasUsed":  lookup.Receipt.GasUsed,
+				"return":   "0x" + hex.EncodeToString(lookup.Receipt.Return),
+			},
+			"method":     uint64(message.Method),
+			"operations": operations,
+		},
+		Idempotent: false,
+	}
+
+	return res, nil
+}
+
+// inclusionTipSet returns the tipset including a message, which is the Rosetta
+// block holding it, from the key of the tipset executing it, which holds its receipt
+func (s *CallAPIService) inclusionTipSet(ctx context.Context, executionKey filTypes.TipSetKey) (*filTypes.TipSet, error) {
+	executed, err := s.node.ChainGetTipSet(ctx, executionKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.node.ChainGetTipSet(ctx, executed.Parents())
+}
@@ -0,0 +1,73 @@
+package call_test
+
+import (
+	"context"
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+)
+
+func TestTransactionStatus(t *testing.T) {
+	msgCid, err := cid.Decode(testMessageCid)
+	require.NoError(t, err)
+	from, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	to, err := address.NewIDAddress(1002)
+	require.NoError(t, err)
+
+	included := testTipSet(t, 100)
+	executed := testTipSet(t, 101)
+
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("StateSearchMsg", mock.Anything, filTypes.EmptyTSK, msgCid, abi.ChainEpoch(50), true).Return(&api.MsgLookup{
+		Message: msgCid,
+		Receipt: filTypes.MessageReceipt{ExitCode: exitcode.SysErrInsufficientFunds, GasUsed: 99},
+		TipSet:  executed.Key(),
+		Height:  executed.Height(),
+	}, nil)
+	fullNodeMock.On("ChainGetMessage", mock.Anything, msgCid).Return(&filTypes.Message{
+		From:  from,
+		To:    to,
+		Value: abi.NewTokenAmount(10),
+	}, nil)
+	fullNodeMock.On("ChainGetTipSet", mock.Anything, executed.Key()).Return(executed, nil)
+	fullNodeMock.On("ChainGetTipSet", mock.Anything, executed.Parents()).Return(included, nil)
+
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.TransactionStatusCall,
+		Parameters:        map[string]interface{}{"cid": testMessageCid, "lookback": 50},
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, int64(100), resp.Result["blockIdentifier"].(map[string]interface{})["index"])
+	assert.Equal(t, int64(exitcode.SysErrInsufficientFunds), resp.Result["receipt"].(map[string]interface{})["exitCode"])
+
+	operations := resp.Result["operations"].([]*rosettaTypes.Operation)
+	require.Len(t, operations, 2)
+	assert.Equal(t, services.OperationStatusFailed, *operations[0].Status)
+	assert.Equal(t, "f01002", operations[1].Account.Address)
+}
+
+func TestTransactionStatusNotFound(t *testing.T) {
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("StateSearchMsg", mock.Anything, filTypes.EmptyTSK, mock.Anything, api.LookbackNoLimit, true).Return(nil, nil)
+
+	_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.TransactionStatusCall,
+		Parameters:        map[string]interface{}{"cid": testMessageCid},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrMessageNotFound.Code, rosettaErr.Code)
+}
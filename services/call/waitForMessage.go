@@ -0,0 +1,126 @@
+package call
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const WaitForMessageCall = "WaitForMessage"
+
+var waitForMessageMethod = Method{
+	Name: WaitForMessageCall,
+	Params: []Param{
+		{Name: "cid", Type: ParamString, Required: true},
+		{Name: "confidence", Type: ParamNumber},
+		{Name: "timeout", Type: ParamNumber},
+	},
+	Handler: (*CallAPIService).WaitForMessage,
+}
+
+const (
+	// DefaultWaitConfidence is the number of confirmations waited for when the request sets none
+	DefaultWaitConfidence = 5
+
+	// DefaultWaitTimeout is the time waited for the message when the request sets none
+	DefaultWaitTimeout = 5 * time.Minute
+
+	// MaxWaitTimeout is the longest time a request can wait for a message
+	MaxWaitTimeout = 30 * time.Minute
+)
+
+// waitForMessageParams are the parameters of a WaitForMessage call
+type waitForMessageParams struct {
+	Cid        string `json:"cid"`
+	Confidence uint64 `json:"confidence"`
+	// Timeout in seconds
+	Timeout uint64 `json:"timeout"`
+}
+
+// WaitForMessage waits until a message is included on chain with the requested
+// confirmations and returns the tipset including it and its receipt
+func (s *CallAPIService) WaitForMessage(
+	ctx context.Context,
+	request *rosettaTypes.CallRequest,
+) (*rosettaTypes.CallResponse, *rosettaTypes.Error) {
+
+	var params waitForMessageParams
+	if errParams := decodeParams(request, &params); errParams != nil {
+		return nil, errParams
+	}
+
+	msgCid, err := cid.Decode(params.Cid)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrInvalidHash, err, false)
+	}
+
+	confidence := params.Confidence
+	if confidence == 0 {
+		confidence = DefaultWaitConfidence
+	}
+
+	timeout := DefaultWaitTimeout
+	if params.Timeout > 0 {
+		timeout = time.Duration(params.Timeout) * time.Second
+	}
+	if timeout > MaxWaitTimeout {
+		return nil, rosetta.BuildError(rosetta.ErrMalformedValue,
+			fmt.Errorf("timeout can not be longer than %d seconds", int64(MaxWaitTimeout.Seconds())), false)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	lookup, err := s.node.StateWaitMsg(waitCtx, msgCid, confidence, api.LookbackNoLimit, true)
+	if err != nil {
+		if errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+			return nil, rosetta.BuildError(services.ErrWaitTimedOut, err, true)
+		}
+		return nil, rosetta.BuildError(services.ErrUnableToGetReceipt, err, true)
+	}
+
+	// The lookup points to the tipset executing the message, which is the child
+	// of the one including it
+	var included *filTypes.TipSet
+	impl := func() {
+		included, err = s.inclusionTipSet(ctx, lookup.TipSet)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, services.LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTipset, err, true)
+	}
+
+	blockId, errBlock := blockIdentifier(included)
+	if errBlock != nil {
+		return nil, errBlock
+	}
+
+	res := &rosettaTypes.CallResponse{
+		Result: map[string]interface{}{
+			"message": lookup.Message.String(),
+			"tipSet": map[string]interface{}{
+				"index": blockId.Index,
+				"hash":  blockId.Hash,
+			},
+			"exitCode": int64(lookup.Receipt.ExitCode),
+			"gasUsed":  lookup.Receipt.GasUsed,
+		},
+		Idempotent: false,
+	}
+
+	return res, nil
+}
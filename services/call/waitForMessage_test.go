@@ -0,0 +1,70 @@
+package call_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services/call"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+func TestWaitForMessage(t *testing.T) {
+	msgCid, err := cid.Decode(testMessageCid)
+	require.NoError(t, err)
+
+	included := testTipSet(t, 100)
+	executed := testTipSet(t, 101)
+
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("StateWaitMsg", mock.Anything, msgCid, uint64(2), api.LookbackNoLimit, true).Return(&api.MsgLookup{
+		Message: msgCid,
+		Receipt: filTypes.MessageReceipt{ExitCode: exitcode.Ok, GasUsed: 1234},
+		TipSet:  executed.Key(),
+		Height:  executed.Height(),
+	}, nil)
+	fullNodeMock.On("ChainGetTipSet", mock.Anything, executed.Key()).Return(executed, nil)
+	fullNodeMock.On("ChainGetTipSet", mock.Anything, executed.Parents()).Return(included, nil)
+
+	resp, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.WaitForMessageCall,
+		Parameters:        map[string]interface{}{"cid": testMessageCid, "confidence": 2},
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, int64(0), resp.Result["exitCode"])
+	assert.Equal(t, int64(1234), resp.Result["gasUsed"])
+	includedHash, err := rosetta.BuildTipSetKeyHash(included.Key())
+	require.NoError(t, err)
+	tipSet := resp.Result["tipSet"].(map[string]interface{})
+	assert.Equal(t, int64(included.Height()), tipSet["index"])
+	assert.Equal(t, *includedHash, tipSet["hash"])
+}
+
+func TestWaitForMessageTimeout(t *testing.T) {
+	s, fullNodeMock := newCallService(t)
+	fullNodeMock.On("StateWaitMsg", mock.Anything, mock.Anything, uint64(call.DefaultWaitConfidence), api.LookbackNoLimit, true).
+		Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return(nil, context.DeadlineExceeded)
+
+	start := time.Now()
+	_, rosettaErr := s.Call(context.Background(), &rosettaTypes.CallRequest{
+		NetworkIdentifier: testNetwork,
+		Method:            call.WaitForMessageCall,
+		Parameters:        map[string]interface{}{"cid": testMessageCid, "timeout": 1},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrWaitTimedOut.Code, rosettaErr.Code)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
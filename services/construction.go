@@ -2,16 +2,16 @@ package services
 
 import (
 	"context"
-	"encoding/base64"
-	"encoding/json"
+	"fmt"
+	"time"
 
 	"github.com/coinbase/rosetta-sdk-go/server"
 	"github.com/coinbase/rosetta-sdk-go/types"
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/api"
-	"github.com/filecoin-project/lotus/build"
 	filTypes "github.com/filecoin-project/lotus/chain/types"
+	filLib "github.com/zondax/rosetta-filecoin-lib"
 	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
 )
 
@@ -64,24 +64,48 @@ const OptionsMethodNumKey = "methodNum"
 // ConstructionMetadataRequest that specifies the params
 const OptionsParamsKey = "params"
 
-// ConstructionAPIService implements the server.ConstructionAPIServicer interface.
-type ConstructionAPIService struct {
-	network *types.NetworkIdentifier
-	node    api.FullNode
+// ConstructionConfig holds the optional behaviours of the construction endpoints
+type ConstructionConfig struct {
+	// PreflightSimulation runs signed messages with StateCall before pushing them
+	// and refuses the ones that would fail
+	PreflightSimulation bool
+	// StrictOptions rejects unknown keys in the options of /construction/metadata
+	StrictOptions bool
+	// NonceReservationTTL enables the NonceManager when not zero, /construction/metadata
+	// then reserves a different nonce for every construction of the same sender
+	NonceReservationTTL time.Duration
 }
 
-var ErrMalformedParams = &types.Error{
-	Code:      1000,
-	Message:   "malformed params",
-	Retriable: false,
+// ConstructionAPIService implements the server.ConstructionAPIServicer interface.
+type ConstructionAPIService struct {
+	network    *types.NetworkIdentifier
+	node       api.FullNode
+	rosettaLib *filLib.RosettaConstructionFilecoin
+	config     ConstructionConfig
+	nonces     *NonceManager
 }
 
 // NewConstructionAPIService creates a new instance of an ConstructionAPIService.
-func NewConstructionAPIService(network *types.NetworkIdentifier, node *api.FullNode) server.ConstructionAPIServicer {
-	return &ConstructionAPIService{
-		network: network,
-		node:    *node,
+// A nil node makes the service run offline, serving only the endpoints that do not need Lotus.
+func NewConstructionAPIService(network *types.NetworkIdentifier, node *api.FullNode, r *filLib.RosettaConstructionFilecoin,
+	config ConstructionConfig) server.ConstructionAPIServicer {
+	service := &ConstructionAPIService{
+		network:    network,
+		node:       *node,
+		rosettaLib: r,
+		config:     config,
 	}
+
+	if *node != nil && config.NonceReservationTTL > 0 {
+		service.nonces = NewNonceManager(*node, config.NonceReservationTTL)
+	}
+
+	return service
+}
+
+// isOffline reports whether the service was created without a Lotus node
+func (c *ConstructionAPIService) isOffline() bool {
+	return c.node == nil
 }
 
 // ConstructionMetadata implements the /construction/metadata endpoint.
@@ -90,70 +114,49 @@ func (c *ConstructionAPIService) ConstructionMetadata(
 	request *types.ConstructionMetadataRequest,
 ) (*types.ConstructionMetadataResponse, *types.Error) {
 	var (
-		addressSenderParsed   address.Address // nolint
-		addressReceiverParsed address.Address // nolint
-		err                   error
-		nonce                 uint64
-		blockInclUint         uint64 = 1
-		message                      = &filTypes.Message{
+		err     error
+		nonce   uint64
+		message = &filTypes.Message{
 			GasLimit: 0, GasFeeCap: filTypes.NewInt(0),
 			GasPremium: filTypes.NewInt(0),
 			Value:      abi.NewTokenAmount(1), // Use "1" as default value for better gas estimations
 		}
 	)
 
+	if c.isOffline() {
+		return nil, ErrUnavailableOffline
+	}
+
 	errNet := rosetta.ValidateNetworkId(ctx, &c.node, request.NetworkIdentifier)
 	if errNet != nil {
 		return nil, errNet
 	}
 
+	opts, errOpts := ParseMetadataOptions(request.Options, c.config.StrictOptions)
+	if errOpts != nil {
+		return nil, errOpts
+	}
+
 	md := make(map[string]interface{})
 
 	if request.Options != nil {
-		// Parse block include epochs - this field is optional
-		blockIncl, ok := request.Options[OptionsBlockInclKey]
-		if ok {
-			blockInclUint = uint64(blockIncl.(float64))
+		if opts.MethodNum != nil {
+			message.Method = *opts.MethodNum
 		}
 
-		// Parse method num - this field is optional
-		methodNum, ok := request.Options[OptionsMethodNumKey]
-		if ok {
-			message.Method = abi.MethodNum(uint64(methodNum.(float64)))
+		if opts.Params != nil {
+			message.Params = opts.Params
 		}
 
-		// Parse params - this field is optional
-		params, ok := request.Options[OptionsParamsKey]
-		if ok {
-			paramsByte, err := base64.StdEncoding.DecodeString(params.(string))
-			if err != nil {
-				return nil, rosetta.BuildError(ErrMalformedParams, err, false)
-			}
-
-			message.Params = paramsByte
+		if opts.Sender != nil {
+			message.From = *opts.Sender
 		}
 
-		// Parse sender address - this field is optional
-		addressSenderRaw, okSender := request.Options[OptionsSenderIDKey]
-		if okSender {
-			addressSenderParsed, err = address.NewFromString(addressSenderRaw.(string)) // nolint
-			if err != nil {
-				return nil, rosetta.BuildError(rosetta.ErrInvalidAccountAddress, err, true)
-			}
-			message.From = addressSenderParsed
-		}
-
-		// Parse receiver address - this field is optional
-		addressReceiverRaw, okReceiver := request.Options[OptionsReceiverIDKey]
-		if okReceiver {
-			addressReceiverParsed, err = address.NewFromString(addressReceiverRaw.(string)) // nolint
-			if err != nil {
-				return nil, rosetta.BuildError(rosetta.ErrInvalidAccountAddress, err, true)
-			}
-			message.To = addressReceiverParsed
+		if opts.Receiver != nil {
+			message.To = *opts.Receiver
 
 			// Get receiver's actor code
-			receiverActor, errAct := c.node.StateGetActor(context.Background(), addressReceiverParsed, filTypes.EmptyTSK)
+			receiverActor, errAct := c.node.StateGetActor(context.Background(), *opts.Receiver, filTypes.EmptyTSK)
 			if errAct != nil {
 				// Actor not found on chain, set an empty field
 				md[DestinationActorIdKey] = ""
@@ -162,49 +165,44 @@ func (c *ConstructionAPIService) ConstructionMetadata(
 			}
 		}
 
-		// Parse value to send - this field is optional
-		valueRaw, okValue := request.Options[OptionsValueKey]
-		if okValue {
-			value, err := filTypes.BigFromString(valueRaw.(string))
-			if err != nil {
-				return nil, rosetta.BuildError(rosetta.ErrMalformedValue, err, false)
-			}
-			message.Value = value
+		if opts.Value != nil {
+			message.Value = *opts.Value
 		}
 
-		if okSender {
-			nonce, err = c.node.MpoolGetNonce(ctx, addressSenderParsed)
-			if err != nil {
-				return nil, rosetta.BuildError(rosetta.ErrUnableToGetNextNonce, err, true)
-			}
-			md[NonceKey] = nonce
-
-			// GasEstimateMessageGas
-			message, err = c.node.GasEstimateMessageGas(ctx, message, &api.MessageSendSpec{MaxFee: filTypes.NewInt(uint64(build.BlockGasLimit))}, filTypes.TipSetKey{})
-			if err != nil {
-				return nil, rosetta.BuildError(rosetta.ErrUnableToEstimateGasLimit, err, true)
+		if opts.Sender != nil {
+			var errGas *types.Error
+			message, errGas = EstimateMessageGas(ctx, c.node, message, opts.BlockIncl, opts.MaxFee)
+			if errGas != nil {
+				return nil, errGas
 			}
-
-			// GasEstimateGasPremium
-			gasPremium, gasErr := c.node.GasEstimateGasPremium(ctx, blockInclUint, addressSenderParsed, message.GasLimit, filTypes.TipSetKey{}) // nolint
+		} else {
+			// We can only estimate gas premium without a sender address
+			gasPremium, gasErr := c.node.GasEstimateGasPremium(ctx, opts.BlockIncl, address.Address{}, message.GasLimit, filTypes.TipSetKey{}) // nolint
 			if gasErr != nil {
 				return nil, rosetta.BuildError(rosetta.ErrUnableToEstimateGasPremium, gasErr, true)
 			}
 			message.GasPremium = gasPremium
+		}
 
-			// GasEstimateFeeCap requires gasPremium to be set on message
-			gasFeeCap, gasErr := c.node.GasEstimateFeeCap(ctx, message, int64(blockInclUint), filTypes.TipSetKey{})
-			if gasErr != nil {
-				return nil, rosetta.BuildError(rosetta.ErrUnableToEstimateGasFeeCap, gasErr, true)
+		if opts.FeeTiers {
+			tiers, errTiers := c.estimateFeeTiers(ctx, message, opts.MaxFee)
+			if errTiers != nil {
+				return nil, errTiers
 			}
-			message.GasFeeCap = gasFeeCap
-		} else {
-			// We can only estimate gas premium without a sender address
-			gasPremium, gasErr := c.node.GasEstimateGasPremium(ctx, blockInclUint, address.Address{}, message.GasLimit, filTypes.TipSetKey{}) // nolint
-			if gasErr != nil {
-				return nil, rosetta.BuildError(rosetta.ErrUnableToEstimateGasPremium, gasErr, true)
+			md[FeeTiersKey] = tiers
+		}
+
+		// The nonce is reserved last, so a failed estimation leaves no reservation behind
+		if opts.Sender != nil {
+			if c.nonces != nil {
+				nonce, err = c.nonces.Reserve(ctx, *opts.Sender)
+			} else {
+				nonce, err = c.node.MpoolGetNonce(ctx, *opts.Sender)
 			}
-			message.GasPremium = gasPremium
+			if err != nil {
+				return nil, rosetta.BuildError(rosetta.ErrUnableToGetNextNonce, err, true)
+			}
+			md[NonceKey] = nonce
 		}
 	}
 
@@ -216,6 +214,9 @@ func (c *ConstructionAPIService) ConstructionMetadata(
 	resp := &types.ConstructionMetadataResponse{
 		Metadata: md,
 	}
+	if message.From != address.Undef {
+		resp.SuggestedFee = suggestedFee(message)
+	}
 
 	return resp, nil
 }
@@ -227,7 +228,11 @@ func (c *ConstructionAPIService) ConstructionSubmit(
 ) (*types.TransactionIdentifierResponse, *types.Error) {
 
 	if request.SignedTransaction == "" {
-		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, nil, true)
+		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, fmt.Errorf("empty signed transaction"), false)
+	}
+
+	if c.isOffline() {
+		return nil, ErrUnavailableOffline
 	}
 
 	err := rosetta.ValidateNetworkId(ctx, &c.node, request.NetworkIdentifier)
@@ -235,22 +240,23 @@ func (c *ConstructionAPIService) ConstructionSubmit(
 		return nil, err
 	}
 
-	rawIn := json.RawMessage(request.SignedTransaction)
-
-	bytes, errJson := rawIn.MarshalJSON()
-	if errJson != nil {
-		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, nil, true)
+	signedTx, errTx := decodeSignedMessage(c.network, request.SignedTransaction)
+	if errTx != nil {
+		return nil, errTx
 	}
 
-	var signedTx filTypes.SignedMessage
-	errUnmarshal := json.Unmarshal(bytes, &signedTx)
-	if errUnmarshal != nil {
-		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, nil, true)
+	if c.config.PreflightSimulation {
+		errSim := c.preflight(ctx, signedTx)
+		if errSim != nil {
+			c.releaseNonce(ctx, &signedTx.Message)
+			return nil, errSim
+		}
 	}
 
-	cid, errTx := c.node.MpoolPush(ctx, &signedTx)
-	if errTx != nil {
-		return nil, rosetta.BuildError(rosetta.ErrUnableToSubmitTx, errTx, true)
+	cid, errPush := c.node.MpoolPush(ctx, signedTx)
+	if errPush != nil {
+		c.releaseNonce(ctx, &signedTx.Message)
+		return nil, rosetta.BuildError(rosetta.ErrUnableToSubmitTx, errPush, true)
 	}
 
 	resp := &types.TransactionIdentifierResponse{
@@ -262,26 +268,15 @@ func (c *ConstructionAPIService) ConstructionSubmit(
 	return resp, nil
 }
 
-func (c *ConstructionAPIService) ConstructionCombine(ctx context.Context, request *types.ConstructionCombineRequest) (*types.ConstructionCombineResponse, *types.Error) {
-	return nil, rosetta.ErrNotImplemented
-}
-
-func (c *ConstructionAPIService) ConstructionDerive(ctx context.Context, request *types.ConstructionDeriveRequest) (*types.ConstructionDeriveResponse, *types.Error) {
-	return nil, rosetta.ErrNotImplemented
-}
-
-func (c *ConstructionAPIService) ConstructionHash(ctx context.Context, request *types.ConstructionHashRequest) (*types.TransactionIdentifierResponse, *types.Error) {
-	return nil, rosetta.ErrNotImplemented
-}
-
-func (c *ConstructionAPIService) ConstructionParse(ctx context.Context, request *types.ConstructionParseRequest) (*types.ConstructionParseResponse, *types.Error) {
-	return nil, rosetta.ErrNotImplemented
-}
-
-func (c *ConstructionAPIService) ConstructionPayloads(ctx context.Context, request *types.ConstructionPayloadsRequest) (*types.ConstructionPayloadsResponse, *types.Error) {
-	return nil, rosetta.ErrNotImplemented
-}
+// releaseNonce frees the nonce reserved for a message that was not pushed, so
+// the next construction of its sender does not leave a gap
+func (c *ConstructionAPIService) releaseNonce(ctx context.Context, message *filTypes.Message) {
+	if c.nonces == nil {
+		return
+	}
 
-func (c *ConstructionAPIService) ConstructionPreprocess(ctx context.Context, request *types.ConstructionPreprocessRequest) (*types.ConstructionPreprocessResponse, *types.Error) {
-	return nil, rosetta.ErrNotImplemented
+	err := c.nonces.Release(ctx, message.From, message.Nonce)
+	if err != nil {
+		rosetta.Logger.Warnf("/construction/submit - unable to release nonce %d of %s: %v", message.Nonce, message.From, err)
+	}
 }
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+	"github.com/filecoin-project/go-state-types/crypto"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/chain/types/ethtypes"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+// Messages sent by f410 (delegated) addresses are signed as EIP-1559 Ethereum
+// transactions, the signature is attached to the filecoin message with the
+// delegated signature type.
+
+// InvokeContractOperationType is the type of the operation calling an EVM contract
+const InvokeContractOperationType = "InvokeContract"
+
+// OperationInputKey is the name of the key in the Metadata map of an
+// InvokeContract operation that specifies the 0x prefixed hex call data
+const OperationInputKey = "input"
+
+// DeriveAddressTypeKey is the name of the key in the Metadata map inside a
+// ConstructionDeriveRequest that specifies which address type to derive
+const DeriveAddressTypeKey = "addressType"
+
+// DelegatedAddressType is the value of DeriveAddressTypeKey deriving f410 addresses
+const DelegatedAddressType = "delegated"
+
+// ethSignatureLength is the length of a r || s || v Ethereum signature
+const ethSignatureLength = 65
+
+// eip155ChainIDs are the EIP-155 chain IDs delegated senders sign for, by
+// network. Lotus takes its own from the build, which matches the network of
+// the node but not necessarily the one of this proxy's build.
+var eip155ChainIDs = map[string]int{
+	mainnetName:      314,
+	"calibrationnet": 314159,
+	"butterflynet":   3141592,
+}
+
+func isDelegated(addr address.Address) bool {
+	return addr.Protocol() == address.Delegated
+}
+
+// deriveDelegatedAddress returns the f410 address of an uncompressed secp256k1 public key
+func deriveDelegatedAddress(uncompressedPubKey []byte) (address.Address, error) {
+	ethAddrBytes, err := ethtypes.EthAddressFromPubKey(uncompressedPubKey)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	ethAddr, err := ethtypes.CastEthAddress(ethAddrBytes)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	return ethAddr.ToFilecoinAddress()
+}
+
+// delegatedTxArgs returns the Ethereum transaction equivalent to a message on network
+func delegatedTxArgs(network *types.NetworkIdentifier, message *filTypes.Message) (*ethtypes.Eth1559TxArgs, error) {
+	chainID, ok := eip155ChainIDs[network.Network]
+	if !ok {
+		return nil, fmt.Errorf("no EIP-155 chain id known for network %s", network.Network)
+	}
+
+	tx, err := ethtypes.Eth1559TxArgsFromUnsignedFilecoinMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	tx.ChainID = chainID
+	return tx, nil
+}
+
+// delegatedSigningDigest returns the keccak hash of the Ethereum transaction
+// equivalent to a message, which is what the sender's key has to sign
+func delegatedSigningDigest(network *types.NetworkIdentifier, message *filTypes.Message) ([]byte, error) {
+	tx, err := delegatedTxArgs(network, message)
+	if err != nil {
+		return nil, err
+	}
+
+	rlp, err := tx.ToRlpUnsignedMsg()
+	if err != nil {
+		return nil, err
+	}
+
+	digest := ethtypes.EthHashFromTxBytes(rlp)
+	return digest[:], nil
+}
+
+// verifyDelegatedSignature checks that a delegated signature was produced by the
+// message sender for network
+func verifyDelegatedSignature(network *types.NetworkIdentifier, signedTx *filTypes.SignedMessage) error {
+	if signedTx.Signature.Type != crypto.SigTypeDelegated {
+		return fmt.Errorf("expected a delegated signature, got type %d", signedTx.Signature.Type)
+	}
+
+	tx, err := delegatedTxArgs(network, &signedTx.Message)
+	if err != nil {
+		return err
+	}
+
+	err = tx.InitialiseSignature(signedTx.Signature)
+	if err != nil {
+		return err
+	}
+
+	sender, err := tx.Sender()
+	if err != nil {
+		return err
+	}
+
+	if sender != signedTx.Message.From {
+		return fmt.Errorf("signature belongs to %s, not to the sender %s", sender, signedTx.Message.From)
+	}
+
+	return nil
+}
+
+func buildInvokeContract(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	to, err := metadataAddress(md, OperationToKey)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := metadataBigInt(md, OperationValueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rawInput, ok := md[OperationInputKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid %s in metadata", OperationInputKey)
+	}
+
+	input, err := hex.DecodeString(strings.TrimPrefix(rawInput, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(input) == 0 {
+		return nil, fmt.Errorf("%s must not be empty, use a Send operation instead", OperationInputKey)
+	}
+
+	// The EVM actor takes the call data as a CBOR byte string
+	inputBytes := abi.CborBytes(input)
+	params, err := encodeParams(&inputBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &messageIntent{
+		from:   from,
+		to:     to,
+		value:  value,
+		method: builtin.MethodsEVM.InvokeContract,
+		params: params,
+	}, nil
+}
+
+func parseInvokeContract(message *filTypes.Message) (map[string]interface{}, bool) {
+	if message.Method != builtin.MethodsEVM.InvokeContract || len(message.Params) == 0 {
+		return nil, false
+	}
+
+	var input abi.CborBytes
+	if err := input.UnmarshalCBOR(bytes.NewReader(message.Params)); err != nil {
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		OperationToKey:    message.To.String(),
+		OperationValueKey: message.Value.String(),
+		OperationInputKey: "0x" + hex.EncodeToString(input),
+	}, true
+}
+
+// delegatedCombine attaches the Ethereum signature of a delegated sender to a message
+func delegatedCombine(network *types.NetworkIdentifier, message *filTypes.Message, sig []byte) (*types.ConstructionCombineResponse, *types.Error) {
+	if len(sig) != ethSignatureLength {
+		return nil, rosetta.BuildError(ErrInvalidSignature, fmt.Errorf("expected a %d bytes signature, got %d", ethSignatureLength, len(sig)), false)
+	}
+
+	data := append([]byte{}, sig...)
+	// Accept the legacy recovery ids used by some Ethereum signers
+	if data[ethSignatureLength-1] >= 27 {
+		data[ethSignatureLength-1] -= 27
+	}
+
+	signedTx := &filTypes.SignedMessage{
+		Message: *message,
+		Signature: crypto.Signature{
+			Type: crypto.SigTypeDelegated,
+			Data: data,
+		},
+	}
+
+	err := verifyDelegatedSignature(network, signedTx)
+	if err != nil {
+		return nil, rosetta.BuildError(ErrInvalidSignature, err, false)
+	}
+
+	return combineResponse(signedTx)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/build/buildconstants"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+// OptionsMaxFeeKey is the name of the key in the Options map inside a
+// ConstructionMetadataRequest that specifies the maximum fee, in attoFIL, the
+// sender is willing to pay
+const OptionsMaxFeeKey = "maxFee"
+
+// OptionsFeeTiersKey is the name of the key in the Options map inside a
+// ConstructionMetadataRequest that asks for the fee tiers. They are left out by
+// default, as every tier needs its own gas premium and fee cap estimations.
+const OptionsFeeTiersKey = "feeTiers"
+
+// DefaultMaxFee is the maximum fee, in attoFIL, used when the options set none
+var DefaultMaxFee = abi.NewTokenAmount(buildconstants.BlockGasLimit)
+
+// FeeTiersKey is the name of the key in the Metadata map inside a
+// ConstructionMetadataResponse that specifies the gas premium and fee cap of
+// every fee tier. The top level gas values are the ones of the requested blockIncl.
+const FeeTiersKey = "feeTiers"
+
+// FeeTierBlockInclKey is the name of the key in a fee tier that specifies the
+// number of epochs the message is expected to be included in
+const FeeTierBlockInclKey = "blockIncl"
+
+// feeTier is an inclusion target offered in the metadata response
+type feeTier struct {
+	name      string
+	blockIncl uint64
+}
+
+// feeTiers are the fee tiers returned by /construction/metadata, from the
+// fastest and most expensive to the slowest
+var feeTiers = []feeTier{
+	{name: "fast", blockIncl: 1},
+	{name: "normal", blockIncl: 5},
+	{name: "slow", blockIncl: 20},
+}
+
+// fees holds the gas values of a message for an inclusion target
+type fees struct {
+	gasPremium abi.TokenAmount
+	gasFeeCap  abi.TokenAmount
+}
+
+// EstimateMessageGas returns a copy of message with the gas values estimated the
+// same way /construction/metadata does, for an inclusion in blockIncl epochs and
+// a fee capped by maxFee (zero for the Lotus default). Gas values already set on
+// the message are kept.
+func EstimateMessageGas(ctx context.Context, node api.FullNode, message *filTypes.Message, blockIncl uint64,
+	maxFee abi.TokenAmount) (*filTypes.Message, *types.Error) {
+	estimated, err := node.GasEstimateMessageGas(ctx, message, &api.MessageSendSpec{MaxFee: maxFee}, filTypes.TipSetKey{})
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToEstimateGasLimit, err, true)
+	}
+
+	keepPremium := !message.GasPremium.NilOrZero()
+	keepFeeCap := !message.GasFeeCap.NilOrZero()
+	if keepPremium && keepFeeCap {
+		return estimated, nil
+	}
+
+	selected, errFees := estimateFees(ctx, node, estimated, blockIncl, maxFee)
+	if errFees != nil {
+		return nil, errFees
+	}
+	if !keepPremium {
+		estimated.GasPremium = selected.gasPremium
+	}
+	if !keepFeeCap {
+		estimated.GasFeeCap = selected.gasFeeCap
+	}
+
+	return estimated, nil
+}
+
+// estimateFees returns the gas premium and fee cap of a message, whose gas limit
+// is already estimated, to be included in blockIncl epochs
+func estimateFees(ctx context.Context, node api.FullNode, message *filTypes.Message, blockIncl uint64,
+	maxFee abi.TokenAmount) (*fees, *types.Error) {
+	gasPremium, err := node.GasEstimateGasPremium(ctx, blockIncl, message.From, message.GasLimit, filTypes.TipSetKey{})
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToEstimateGasPremium, err, true)
+	}
+
+	// GasEstimateFeeCap requires gasPremium to be set on message
+	estimated := *message
+	estimated.GasPremium = gasPremium
+	gasFeeCap, err := node.GasEstimateFeeCap(ctx, &estimated, int64(blockIncl), filTypes.TipSetKey{})
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToEstimateGasFeeCap, err, true)
+	}
+
+	gasPremium, gasFeeCap = capFees(message.GasLimit, gasPremium, gasFeeCap, maxFee)
+	return &fees{gasPremium: gasPremium, gasFeeCap: gasFeeCap}, nil
+}
+
+// estimateFeeTiers returns the gas values of every fee tier. Without a sender
+// the gas limit is unknown and only the gas premium can be estimated.
+func (c *ConstructionAPIService) estimateFeeTiers(ctx context.Context, message *filTypes.Message,
+	maxFee abi.TokenAmount) (map[string]interface{}, *types.Error) {
+	tiers := make(map[string]interface{}, len(feeTiers))
+	for _, tier := range feeTiers {
+		tierMd := map[string]interface{}{
+			FeeTierBlockInclKey: tier.blockIncl,
+		}
+
+		if message.From == address.Undef {
+			gasPremium, err := c.node.GasEstimateGasPremium(ctx, tier.blockIncl, address.Address{}, message.GasLimit, filTypes.TipSetKey{})
+			if err != nil {
+				return nil, rosetta.BuildError(rosetta.ErrUnableToEstimateGasPremium, err, true)
+			}
+			tierMd[GasPremiumKey] = gasPremium.String()
+		} else {
+			tierFees, errFees := estimateFees(ctx, c.node, message, tier.blockIncl, maxFee)
+			if errFees != nil {
+				return nil, errFees
+			}
+			tierMd[GasPremiumKey] = tierFees.gasPremium.String()
+			tierMd[GasFeeCapKey] = tierFees.gasFeeCap.String()
+		}
+
+		tiers[tier.name] = tierMd
+	}
+
+	return tiers, nil
+}
+
+// capFees lowers the fee cap so that the message can not cost more than maxFee,
+// the same way Lotus caps its own estimations. A zero maxFee leaves them untouched.
+func capFees(gasLimit int64, gasPremium, gasFeeCap, maxFee abi.TokenAmount) (abi.TokenAmount, abi.TokenAmount) {
+	if maxFee.IsZero() || gasLimit <= 0 {
+		return gasPremium, gasFeeCap
+	}
+
+	maxFeeCap := big.Div(maxFee, big.NewInt(gasLimit))
+	if gasFeeCap.GreaterThan(maxFeeCap) {
+		gasFeeCap = maxFeeCap
+	}
+	if gasPremium.GreaterThan(gasFeeCap) {
+		gasPremium = gasFeeCap
+	}
+
+	return gasPremium, gasFeeCap
+}
+
+// suggestedFee returns the maximum fee a message can cost
+func suggestedFee(message *filTypes.Message) []*types.Amount {
+	return []*types.Amount{
+		{
+			Value:    big.Mul(message.GasFeeCap, big.NewInt(message.GasLimit)).String(),
+			Currency: rosetta.GetCurrencyData(),
+		},
+	}
+}
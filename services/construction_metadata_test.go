@@ -0,0 +1,108 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+)
+
+func TestConstructionMetadataFeeTiers(t *testing.T) {
+	sender, err := address.NewFromString(testReceiver)
+	require.NoError(t, err)
+
+	c, fullNodeMock := newOnlineConstructionService(t)
+	fullNodeMock.On("MpoolGetNonce", mock.Anything, sender).Return(uint64(3), nil)
+	fullNodeMock.On("GasEstimateMessageGas", mock.Anything, mock.Anything, &api.MessageSendSpec{MaxFee: abi.NewTokenAmount(500000)}, filTypes.EmptyTSK).
+		Return(func(_ context.Context, msg *filTypes.Message, _ *api.MessageSendSpec, _ filTypes.TipSetKey) *filTypes.Message {
+			estimated := *msg
+			estimated.GasLimit = 1000
+			return &estimated
+		}, nil)
+	// Premiums grow with the inclusion speed, fee caps exceed the max fee and get capped
+	for blockIncl, premium := range map[uint64]int64{1: 800, 5: 300, 20: 100} {
+		fullNodeMock.On("GasEstimateGasPremium", mock.Anything, blockIncl, sender, int64(1000), filTypes.EmptyTSK).
+			Return(abi.NewTokenAmount(premium), nil)
+	}
+	fullNodeMock.On("GasEstimateFeeCap", mock.Anything, mock.Anything, mock.Anything, filTypes.EmptyTSK).
+		Return(abi.NewTokenAmount(1000), nil)
+
+	resp, rosettaErr := c.ConstructionMetadata(context.Background(), &types.ConstructionMetadataRequest{
+		NetworkIdentifier: testNetwork,
+		Options: map[string]interface{}{
+			services.OptionsSenderIDKey:  testReceiver,
+			services.OptionsBlockInclKey: float64(5),
+			services.OptionsMaxFeeKey:    "500000",
+			services.OptionsFeeTiersKey:  true,
+		},
+	})
+	require.Nil(t, rosettaErr)
+
+	assert.Equal(t, "300", resp.Metadata[services.GasPremiumKey])
+	assert.Equal(t, "500", resp.Metadata[services.GasFeeCapKey])
+	require.Len(t, resp.SuggestedFee, 1)
+	assert.Equal(t, "500000", resp.SuggestedFee[0].Value)
+
+	tiers := resp.Metadata[services.FeeTiersKey].(map[string]interface{})
+	require.Len(t, tiers, 3)
+	fast := tiers["fast"].(map[string]interface{})
+	assert.Equal(t, "500", fast[services.GasPremiumKey])
+	assert.Equal(t, "500", fast[services.GasFeeCapKey])
+	slow := tiers["slow"].(map[string]interface{})
+	assert.Equal(t, uint64(20), slow[services.FeeTierBlockInclKey])
+	assert.Equal(t, "100", slow[services.GasPremiumKey])
+}
+
+func TestConstructionMetadataWithoutFeeTiers(t *testing.T) {
+	sender, err := address.NewFromString(testReceiver)
+	require.NoError(t, err)
+
+	// Only the requested blockIncl is estimated, with the default max fee
+	c, fullNodeMock := newOnlineConstructionService(t)
+	fullNodeMock.On("MpoolGetNonce", mock.Anything, sender).Return(uint64(3), nil)
+	fullNodeMock.On("GasEstimateMessageGas", mock.Anything, mock.Anything, &api.MessageSendSpec{MaxFee: services.DefaultMaxFee}, filTypes.EmptyTSK).
+		Return(func(_ context.Context, msg *filTypes.Message, _ *api.MessageSendSpec, _ filTypes.TipSetKey) *filTypes.Message {
+			estimated := *msg
+			estimated.GasLimit = 1000
+			return &estimated
+		}, nil)
+	fullNodeMock.On("GasEstimateGasPremium", mock.Anything, uint64(1), sender, int64(1000), filTypes.EmptyTSK).
+		Return(abi.NewTokenAmount(800), nil).Once()
+	fullNodeMock.On("GasEstimateFeeCap", mock.Anything, mock.Anything, mock.Anything, filTypes.EmptyTSK).
+		Return(abi.NewTokenAmount(1000), nil).Once()
+
+	resp, rosettaErr := c.ConstructionMetadata(context.Background(), &types.ConstructionMetadataRequest{
+		NetworkIdentifier: testNetwork,
+		Options:           map[string]interface{}{services.OptionsSenderIDKey: testReceiver},
+	})
+	require.Nil(t, rosettaErr)
+
+	assert.Equal(t, "800", resp.Metadata[services.GasPremiumKey])
+	assert.NotContains(t, resp.Metadata, services.FeeTiersKey)
+}
+
+func TestConstructionMetadataFailedEstimationReservesNoNonce(t *testing.T) {
+	sender, err := address.NewFromString(testReceiver)
+	require.NoError(t, err)
+
+	// MpoolGetNonce is not mocked, reserving a nonce would fail the test
+	c, fullNodeMock := newConfiguredConstructionService(t, services.ConstructionConfig{NonceReservationTTL: time.Minute})
+	fullNodeMock.On("GasEstimateMessageGas", mock.Anything, mock.Anything, mock.Anything, filTypes.EmptyTSK).
+		Return(nil, errors.New("estimation failed"))
+
+	_, rosettaErr := c.ConstructionMetadata(context.Background(), &types.ConstructionMetadataRequest{
+		NetworkIdentifier: testNetwork,
+		Options:           map[string]interface{}{services.OptionsSenderIDKey: sender.String()},
+	})
+	require.NotNil(t, rosettaErr)
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/builtin"
+	"github.com/filecoin-project/go-state-types/builtin/v17/miner"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// WithdrawBalanceOperationType is the type of the operation withdrawing available funds from a miner
+	WithdrawBalanceOperationType = "WithdrawBalance"
+
+	// ChangeOwnerAddressOperationType is the type of the operation proposing or confirming a new miner owner
+	ChangeOwnerAddressOperationType = "ChangeOwnerAddress"
+
+	// ChangeWorkerAddressOperationType is the type of the operation changing the miner worker and control addresses
+	ChangeWorkerAddressOperationType = "ChangeWorkerAddress"
+
+	// ConfirmChangeWorkerAddressOperationType is the type of the operation confirming a pending worker change
+	ConfirmChangeWorkerAddressOperationType = "ConfirmChangeWorkerAddress"
+
+	// ChangeBeneficiaryOperationType is the type of the operation proposing or confirming a new miner beneficiary
+	ChangeBeneficiaryOperationType = "ChangeBeneficiary"
+
+	// ChangePeerIDOperationType is the type of the operation changing the miner libp2p peer id
+	ChangePeerIDOperationType = "ChangePeerID"
+
+	// RepayDebtOperationType is the type of the operation repaying the miner fee debt
+	RepayDebtOperationType = "RepayDebt"
+)
+
+// OperationMinerKey is the name of the key in the Metadata map of a miner
+// operation that specifies the miner actor address
+const OperationMinerKey = "miner"
+
+// OperationAmountKey is the name of the key in the Metadata map of a
+// WithdrawBalance operation that specifies the requested amount
+const OperationAmountKey = "amount"
+
+// OperationOwnerKey is the name of the key in the Metadata map of a
+// ChangeOwnerAddress operation that specifies the new owner
+const OperationOwnerKey = "owner"
+
+// OperationWorkerKey is the name of the key in the Metadata map of a
+// ChangeWorkerAddress operation that specifies the new worker
+const OperationWorkerKey = "worker"
+
+// OperationControlAddressesKey is the name of the key in the Metadata map of a
+// ChangeWorkerAddress operation that specifies the new control addresses
+const OperationControlAddressesKey = "controlAddresses"
+
+// OperationBeneficiaryKey is the name of the key in the Metadata map of a
+// ChangeBeneficiary operation that specifies the new beneficiary
+const OperationBeneficiaryKey = "beneficiary"
+
+// OperationQuotaKey is the name of the key in the Metadata map of a
+// ChangeBeneficiary operation that specifies the beneficiary quota
+const OperationQuotaKey = "quota"
+
+// OperationExpirationKey is the name of the key in the Metadata map of a
+// ChangeBeneficiary operation that specifies the epoch the beneficiary expires
+const OperationExpirationKey = "expiration"
+
+// OperationPeerIDKey is the name of the key in the Metadata map of a
+// ChangePeerID operation that specifies the new peer id
+const OperationPeerIDKey = "peerId"
+
+func buildWithdrawBalance(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	amount, err := metadataBigInt(md, OperationAmountKey)
+	if err != nil {
+		return nil, err
+	}
+	if amount.Sign() <= 0 {
+		return nil, fmt.Errorf("%s must be positive", OperationAmountKey)
+	}
+
+	return minerIntent(from, md, builtin.MethodsMiner.WithdrawBalance, &miner.WithdrawBalanceParams{
+		AmountRequested: amount,
+	})
+}
+
+func parseWithdrawBalance(message *filTypes.Message) (map[string]interface{}, bool) {
+	var params miner.WithdrawBalanceParams
+	if !minerMessage(message, builtin.MethodsMiner.WithdrawBalance) || !decodeParams(message.Params, &params) {
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		OperationMinerKey:  message.To.String(),
+		OperationAmountKey: params.AmountRequested.String(),
+	}, true
+}
+
+func buildChangeOwnerAddress(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	owner, err := metadataAddress(md, OperationOwnerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return minerIntent(from, md, builtin.MethodsMiner.ChangeOwnerAddress, &owner)
+}
+
+func parseChangeOwnerAddress(message *filTypes.Message) (map[string]interface{}, bool) {
+	var owner address.Address
+	if !minerMessage(message, builtin.MethodsMiner.ChangeOwnerAddress) || !decodeParams(message.Params, &owner) {
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		OperationMinerKey: message.To.String(),
+		OperationOwnerKey: owner.String(),
+	}, true
+}
+
+func buildChangeWorkerAddress(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	worker, err := metadataAddress(md, OperationWorkerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	controlAddrs, err := metadataOptionalAddresses(md, OperationControlAddressesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return minerIntent(from, md, builtin.MethodsMiner.ChangeWorkerAddress, &miner.ChangeWorkerAddressParams{
+		NewWorker:       worker,
+		NewControlAddrs: controlAddrs,
+	})
+}
+
+func parseChangeWorkerAddress(message *filTypes.Message) (map[string]interface{}, bool) {
+	var params miner.ChangeWorkerAddressParams
+	if !minerMessage(message, builtin.MethodsMiner.ChangeWorkerAddress) || !decodeParams(message.Params, &params) {
+		return nil, false
+	}
+
+	controlAddrs := make([]string, 0, len(params.NewControlAddrs))
+	for _, addr := range params.NewControlAddrs {
+		controlAddrs = append(controlAddrs, addr.String())
+	}
+
+	return map[string]interface{}{
+		OperationMinerKey:            message.To.String(),
+		OperationWorkerKey:           params.NewWorker.String(),
+		OperationControlAddressesKey: controlAddrs,
+	}, true
+}
+
+func buildConfirmChangeWorkerAddress(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	return minerIntent(from, md, builtin.MethodsMiner.ConfirmChangeWorkerAddress, nil)
+}
+
+func parseConfirmChangeWorkerAddress(message *filTypes.Message) (map[string]interface{}, bool) {
+	if !message.Value.IsZero() {
+		return nil, false
+	}
+	return parseNoParams(message, builtin.MethodsMiner.ConfirmChangeWorkerAddress)
+}
+
+func buildChangeBeneficiary(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	beneficiary, err := metadataAddress(md, OperationBeneficiaryKey)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := metadataBigInt(md, OperationQuotaKey)
+	if err != nil {
+		return nil, err
+	}
+	if quota.Sign() < 0 {
+		return nil, fmt.Errorf("%s must not be negative", OperationQuotaKey)
+	}
+
+	expiration, err := metadataUint64(md, OperationExpirationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return minerIntent(from, md, builtin.MethodsMiner.ChangeBeneficiary, &miner.ChangeBeneficiaryParams{
+		NewBeneficiary: beneficiary,
+		NewQuota:       quota,
+		NewExpiration:  abi.ChainEpoch(expiration),
+	})
+}
+
+func parseChangeBeneficiary(message *filTypes.Message) (map[string]interface{}, bool) {
+	var params miner.ChangeBeneficiaryParams
+	if !minerMessage(message, builtin.MethodsMiner.ChangeBeneficiary) || !decodeParams(message.Params, &params) || params.NewExpiration < 0 {
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		OperationMinerKey:       message.To.String(),
+		OperationBeneficiaryKey: params.NewBeneficiary.String(),
+		OperationQuotaKey:       params.NewQuota.String(),
+		OperationExpirationKey:  uint64(params.NewExpiration),
+	}, true
+}
+
+func buildChangePeerID(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	rawPeerID, ok := md[OperationPeerIDKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid %s in metadata", OperationPeerIDKey)
+	}
+
+	peerID, err := peer.Decode(rawPeerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return minerIntent(from, md, builtin.MethodsMiner.ChangePeerID, &miner.ChangePeerIDParams{
+		NewID: abi.PeerID(peerID),
+	})
+}
+
+func parseChangePeerID(message *filTypes.Message) (map[string]interface{}, bool) {
+	var params miner.ChangePeerIDParams
+	if !minerMessage(message, builtin.MethodsMiner.ChangePeerID) || !decodeParams(message.Params, &params) {
+		return nil, false
+	}
+
+	peerID, err := peer.IDFromBytes(params.NewID)
+	if err != nil {
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		OperationMinerKey:  message.To.String(),
+		OperationPeerIDKey: peerID.String(),
+	}, true
+}
+
+func buildRepayDebt(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	value, err := metadataBigInt(md, OperationValueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	intent, err := minerIntent(from, md, builtin.MethodsMiner.RepayDebt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The debt is repaid from the message value and the miner balance
+	intent.value = value
+	return intent, nil
+}
+
+func parseRepayDebt(message *filTypes.Message) (map[string]interface{}, bool) {
+	md, ok := parseNoParams(message, builtin.MethodsMiner.RepayDebt)
+	if !ok {
+		return nil, false
+	}
+
+	md[OperationValueKey] = message.Value.String()
+	return md, true
+}
+
+// minerIntent returns the message calling a miner method, params may be nil for
+// methods without params
+func minerIntent(from address.Address, md map[string]interface{}, method abi.MethodNum, params cborMarshaler) (*messageIntent, error) {
+	minerAddr, err := metadataAddress(md, OperationMinerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var encoded []byte
+	if params != nil {
+		encoded, err = encodeParams(params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &messageIntent{
+		from:   from,
+		to:     minerAddr,
+		value:  big.Zero(),
+		method: method,
+		params: encoded,
+	}, nil
+}
+
+// minerMessage tells whether a message calls a miner method without sending funds
+func minerMessage(message *filTypes.Message, method abi.MethodNum) bool {
+	return message.Method == method && message.Value.IsZero()
+}
+
+// parseNoParams returns the operation metadata of a call to a miner method without params
+func parseNoParams(message *filTypes.Message, method abi.MethodNum) (map[string]interface{}, bool) {
+	if message.Method != method || len(message.Params) > 0 {
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		OperationMinerKey: message.To.String(),
+	}, true
+}
+
+// metadataOptionalAddresses reads an optional list of addresses from a metadata map
+func metadataOptionalAddresses(md map[string]interface{}, key string) ([]address.Address, error) {
+	raw, ok := md[key]
+	if !ok {
+		return []address.Address{}, nil
+	}
+
+	var strs []string
+	switch v := raw.(type) {
+	case []string:
+		strs = v
+	case []interface{}:
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid type %T in %s", item, key)
+			}
+			strs = append(strs, str)
+		}
+	default:
+		return nil, fmt.Errorf("invalid type %T for %s", raw, key)
+	}
+
+	addrs := make([]address.Address, 0, len(strs))
+	for _, str := range strs {
+		addr, err := address.NewFromString(str)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+
+	return addrs, nil
+}
@@ -0,0 +1,615 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+	"github.com/filecoin-project/go-state-types/crypto"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/minio/blake2b-simd"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+// The endpoints in this file do not need a Lotus node, so they are served
+// both online and in offline mode. Combining a message from an ID sender is
+// the exception, as the sender must be resolved to check the signer.
+
+// SendOperationType is the type of the operations describing a plain transfer
+const SendOperationType = "Send"
+
+// mainnetName is the network name Lotus reports for mainnet
+const mainnetName = "mainnet"
+
+// messageIntent holds the parts of a message that are described by the
+// operations of a construction request
+type messageIntent struct {
+	from   address.Address
+	to     address.Address
+	value  abi.TokenAmount
+	method abi.MethodNum
+	params []byte
+}
+
+// ConstructionDerive implements the /construction/derive endpoint.
+func (c *ConstructionAPIService) ConstructionDerive(
+	ctx context.Context,
+	request *types.ConstructionDeriveRequest,
+) (*types.ConstructionDeriveResponse, *types.Error) {
+	if request.PublicKey == nil || request.PublicKey.CurveType != types.Secp256k1 {
+		return nil, rosetta.BuildError(ErrInvalidPublicKey, fmt.Errorf("only %s public keys are supported", types.Secp256k1), false)
+	}
+
+	// Rosetta public keys are usually compressed, filecoin addresses are derived from the uncompressed form
+	pubKey, err := secp256k1.ParsePubKey(request.PublicKey.Bytes)
+	if err != nil {
+		return nil, rosetta.BuildError(ErrInvalidPublicKey, err, false)
+	}
+
+	var addr string
+	if request.Metadata[DeriveAddressTypeKey] == DelegatedAddressType {
+		delegated, errAddr := deriveDelegatedAddress(pubKey.SerializeUncompressed())
+		if errAddr != nil {
+			return nil, rosetta.BuildError(ErrInvalidPublicKey, errAddr, false)
+		}
+		addr = delegated.String()
+	} else {
+		addr, err = c.rosettaLib.DeriveFromPublicKey(pubKey.SerializeUncompressed(), addressNetwork(c.network))
+		if err != nil {
+			return nil, rosetta.BuildError(ErrInvalidPublicKey, err, false)
+		}
+	}
+
+	resp := &types.ConstructionDeriveResponse{
+		AccountIdentifier: &types.AccountIdentifier{
+			Address: addr,
+		},
+	}
+
+	return resp, nil
+}
+
+// ConstructionPreprocess implements the /construction/preprocess endpoint.
+func (c *ConstructionAPIService) ConstructionPreprocess(
+	ctx context.Context,
+	request *types.ConstructionPreprocessRequest,
+) (*types.ConstructionPreprocessResponse, *types.Error) {
+	intent, errOps := intentFromOperations(request.Operations)
+	if errOps != nil {
+		return nil, errOps
+	}
+
+	options := make(map[string]interface{})
+	options[OptionsSenderIDKey] = intent.from.String()
+	options[OptionsReceiverIDKey] = intent.to.String()
+	options[OptionsValueKey] = intent.value.String()
+	if intent.method != builtin.MethodSend {
+		options[OptionsMethodNumKey] = uint64(intent.method)
+	}
+	if len(intent.params) > 0 {
+		options[OptionsParamsKey] = base64.StdEncoding.EncodeToString(intent.params)
+	}
+
+	resp := &types.ConstructionPreprocessResponse{
+		Options: options,
+		RequiredPublicKeys: []*types.AccountIdentifier{
+			{Address: intent.from.String()},
+		},
+	}
+
+	return resp, nil
+}
+
+// ConstructionPayloads implements the /construction/payloads endpoint.
+func (c *ConstructionAPIService) ConstructionPayloads(
+	ctx context.Context,
+	request *types.ConstructionPayloadsRequest,
+) (*types.ConstructionPayloadsResponse, *types.Error) {
+	intent, errOps := intentFromOperations(request.Operations)
+	if errOps != nil {
+		return nil, errOps
+	}
+
+	message, errMsg := messageFromIntent(intent, request.Metadata)
+	if errMsg != nil {
+		return nil, errMsg
+	}
+
+	digest, err := payloadDigest(c.network, message)
+	if err != nil {
+		return nil, rosetta.BuildError(ErrInvalidOperations, err, false)
+	}
+
+	unsignedTx, err := json.Marshal(message)
+	if err != nil {
+		return nil, rosetta.BuildError(ErrMalformedTransaction, err, false)
+	}
+
+	resp := &types.ConstructionPayloadsResponse{
+		UnsignedTransaction: string(unsignedTx),
+		Payloads: []*types.SigningPayload{
+			{
+				AccountIdentifier: &types.AccountIdentifier{
+					Address: intent.from.String(),
+				},
+				Bytes:         digest,
+				SignatureType: types.EcdsaRecovery,
+			},
+		},
+	}
+
+	return resp, nil
+}
+
+// ConstructionParse implements the /construction/parse endpoint.
+func (c *ConstructionAPIService) ConstructionParse(
+	ctx context.Context,
+	request *types.ConstructionParseRequest,
+) (*types.ConstructionParseResponse, *types.Error) {
+	var message *filTypes.Message
+	if request.Signed {
+		signedTx, errTx := decodeSignedMessage(c.network, request.Transaction)
+		if errTx != nil {
+			return nil, errTx
+		}
+		message = &signedTx.Message
+	} else {
+		message = &filTypes.Message{}
+		err := json.Unmarshal([]byte(request.Transaction), message)
+		if err != nil {
+			return nil, rosetta.BuildError(ErrMalformedTransaction, err, false)
+		}
+	}
+
+	operations, errOps := OperationsFromMessage(message)
+	if errOps != nil {
+		return nil, errOps
+	}
+
+	resp := &types.ConstructionParseResponse{
+		Operations: operations,
+	}
+	if request.Signed {
+		resp.AccountIdentifierSigners = []*types.AccountIdentifier{
+			{Address: message.From.String()},
+		}
+	}
+
+	return resp, nil
+}
+
+// ConstructionCombine implements the /construction/combine endpoint.
+func (c *ConstructionAPIService) ConstructionCombine(
+	ctx context.Context,
+	request *types.ConstructionCombineRequest,
+) (*types.ConstructionCombineResponse, *types.Error) {
+	var message filTypes.Message
+	err := json.Unmarshal([]byte(request.UnsignedTransaction), &message)
+	if err != nil {
+		return nil, rosetta.BuildError(ErrMalformedTransaction, err, false)
+	}
+
+	if len(request.Signatures) != 1 {
+		return nil, rosetta.BuildError(ErrInvalidSignature, fmt.Errorf("expected 1 signature, got %d", len(request.Signatures)), false)
+	}
+
+	signature := request.Signatures[0]
+	if signature.SignatureType != types.EcdsaRecovery {
+		return nil, rosetta.BuildError(ErrInvalidSignature, fmt.Errorf("unsupported signature type %s", signature.SignatureType), false)
+	}
+
+	if isDelegated(message.From) {
+		return delegatedCombine(c.network, &message, signature.Bytes)
+	}
+
+	if signature.PublicKey == nil {
+		return nil, rosetta.BuildError(ErrInvalidPublicKey, fmt.Errorf("missing public key"), false)
+	}
+
+	pubKey, err := secp256k1.ParsePubKey(signature.PublicKey.Bytes)
+	if err != nil {
+		return nil, rosetta.BuildError(ErrInvalidPublicKey, err, false)
+	}
+
+	err = c.rosettaLib.VerifyRaw(message.Cid().Bytes(), pubKey.SerializeUncompressed(), signature.Bytes)
+	if err != nil {
+		return nil, rosetta.BuildError(ErrInvalidSignature, err, false)
+	}
+
+	errSigner := c.checkSigner(ctx, message.From, pubKey)
+	if errSigner != nil {
+		return nil, errSigner
+	}
+
+	signedTx := &filTypes.SignedMessage{
+		Message: message,
+		Signature: crypto.Signature{
+			Type: crypto.SigTypeSecp256k1,
+			Data: signature.Bytes,
+		},
+	}
+
+	return combineResponse(signedTx)
+}
+
+// checkSigner verifies that pubKey is the key of the sender. ID senders are
+// resolved to their key address, which needs a Lotus node.
+func (c *ConstructionAPIService) checkSigner(ctx context.Context, sender address.Address, pubKey *secp256k1.PublicKey) *types.Error {
+	signer, err := address.NewSecp256k1Address(pubKey.SerializeUncompressed())
+	if err != nil {
+		return rosetta.BuildError(ErrInvalidPublicKey, err, false)
+	}
+
+	if sender.Protocol() == address.ID {
+		if c.isOffline() {
+			return ErrUnavailableOffline
+		}
+
+		impl := func() {
+			sender, err = c.node.StateAccountKey(ctx, sender, filTypes.EmptyTSK)
+		}
+
+		errTimeOut := rosettaTools.WrapWithTimeout(impl, LotusCallTimeOut)
+		if errTimeOut != nil {
+			return rosetta.ErrLotusCallTimedOut
+		}
+
+		if err != nil {
+			return rosetta.BuildError(ErrUnableToGetActor, err, true)
+		}
+	}
+
+	if signer != sender {
+		return rosetta.BuildError(ErrInvalidSignature, fmt.Errorf("signature belongs to %s, not to the sender %s", signer, sender), false)
+	}
+
+	return nil
+}
+
+// ConstructionHash implements the /construction/hash endpoint.
+func (c *ConstructionAPIService) ConstructionHash(
+	ctx context.Context,
+	request *types.ConstructionHashRequest,
+) (*types.TransactionIdentifierResponse, *types.Error) {
+	// Delegated signatures are verified while decoding
+	signedTx, errTx := decodeSignedMessage(c.network, request.SignedTransaction)
+	if errTx != nil {
+		return nil, errTx
+	}
+
+	var hash string
+	switch signedTx.Signature.Type {
+	case crypto.SigTypeDelegated:
+		hash = signedTx.Cid().String()
+	case crypto.SigTypeSecp256k1:
+		// The signer is checked the same way as in combine, which resolves ID senders
+		pubKey, _, err := ecdsa.RecoverCompact(compactSignature(signedTx.Signature.Data), signingDigest(&signedTx.Message))
+		if err != nil {
+			return nil, rosetta.BuildError(ErrInvalidSignature, err, false)
+		}
+		errSigner := c.checkSigner(ctx, signedTx.Message.From, pubKey)
+		if errSigner != nil {
+			return nil, errSigner
+		}
+		hash = signedTx.Cid().String()
+	default:
+		// The lib verifies the signature before hashing, and only reads JSON
+		signedJson, err := json.Marshal(signedTx)
+		if err != nil {
+			return nil, rosetta.BuildError(ErrMalformedTransaction, err, false)
+		}
+		hash, err = c.rosettaLib.Hash(string(signedJson))
+		if err != nil {
+			return nil, rosetta.BuildError(ErrMalformedTransaction, err, false)
+		}
+	}
+
+	resp := &types.TransactionIdentifierResponse{
+		TransactionIdentifier: &types.TransactionIdentifier{
+			Hash: hash,
+		},
+	}
+
+	return resp, nil
+}
+
+// intentFromOperations validates the operations of a construction request and
+// extracts the message they describe.
+// A transfer is described by two Send operations, a negative one for the sender
+// and a positive one for the receiver. Any other transaction is described by a
+// single typed operation, see constructionOperations.
+func intentFromOperations(operations []*types.Operation) (*messageIntent, *types.Error) {
+	if len(operations) == 1 && operations[0].Type != SendOperationType {
+		intent, err := intentFromOperation(operations[0])
+		if err != nil {
+			return nil, rosetta.BuildError(ErrInvalidOperations, err, false)
+		}
+		return intent, nil
+	}
+
+	if len(operations) != 2 {
+		return nil, rosetta.BuildError(ErrInvalidOperations, fmt.Errorf("expected 2 operations, got %d", len(operations)), false)
+	}
+
+	var sender, receiver *types.Operation
+	for _, op := range operations {
+		if op.Type != SendOperationType {
+			return nil, rosetta.BuildError(ErrInvalidOperations, fmt.Errorf("unsupported operation type %s", op.Type), false)
+		}
+		if op.Account == nil || op.Amount == nil {
+			return nil, rosetta.BuildError(ErrInvalidOperations, fmt.Errorf("operation %d has no account or amount", op.OperationIdentifier.Index), false)
+		}
+
+		amount, ok := new(big.Int).SetString(op.Amount.Value, 10)
+		if !ok {
+			return nil, rosetta.BuildError(rosetta.ErrMalformedValue, fmt.Errorf("invalid amount %s", op.Amount.Value), false)
+		}
+
+		// The sign of a zero amount is only kept by its string
+		if amount.Sign() < 0 || strings.HasPrefix(op.Amount.Value, "-") {
+			sender = op
+		} else {
+			receiver = op
+		}
+	}
+
+	if sender == nil || receiver == nil {
+		return nil, rosetta.BuildError(ErrInvalidOperations, fmt.Errorf("expected one negative and one positive amount"), false)
+	}
+
+	value, err := filTypes.BigFromString(receiver.Amount.Value)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, err, false)
+	}
+	if sender.Amount.Value != "-"+receiver.Amount.Value {
+		return nil, rosetta.BuildError(ErrInvalidOperations, fmt.Errorf("sender and receiver amounts do not match"), false)
+	}
+
+	from, err := address.NewFromString(sender.Account.Address)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrInvalidAccountAddress, err, false)
+	}
+
+	to, err := address.NewFromString(receiver.Account.Address)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrInvalidAccountAddress, err, false)
+	}
+
+	method := builtin.MethodSend
+	// Transfers to and from delegated addresses must invoke the EVM actor
+	if isDelegated(to) || isDelegated(from) {
+		method = builtin.MethodsEVM.InvokeContract
+	}
+
+	// Delegated senders sign Ethereum transactions, which can only target ID or delegated addresses
+	if isDelegated(from) && to.Protocol() != address.ID && !isDelegated(to) {
+		return nil, rosetta.BuildError(ErrInvalidOperations, fmt.Errorf("delegated sender cannot send to %s, use its ID or delegated address", to), false)
+	}
+
+	return &messageIntent{
+		from:   from,
+		to:     to,
+		value:  value,
+		method: method,
+	}, nil
+}
+
+// OperationsFromMessage is the inverse of intentFromOperations
+func OperationsFromMessage(message *filTypes.Message) ([]*types.Operation, *types.Error) {
+	isTransfer := message.Method == builtin.MethodSend ||
+		(message.Method == builtin.MethodsEVM.InvokeContract && len(message.Params) == 0)
+	if !isTransfer {
+		op, ok := operationFromMessage(message)
+		if !ok {
+			return nil, rosetta.BuildError(ErrInvalidOperations, fmt.Errorf("unsupported method %d", message.Method), false)
+		}
+		return []*types.Operation{op}, nil
+	}
+
+	value := message.Value.String()
+	operations := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 0},
+			Type:                SendOperationType,
+			Account:             &types.AccountIdentifier{Address: message.From.String()},
+			Amount: &types.Amount{
+				Value:    "-" + value,
+				Currency: rosetta.GetCurrencyData(),
+			},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 1},
+			RelatedOperations:   []*types.OperationIdentifier{{Index: 0}},
+			Type:                SendOperationType,
+			Account:             &types.AccountIdentifier{Address: message.To.String()},
+			Amount: &types.Amount{
+				Value:    value,
+				Currency: rosetta.GetCurrencyData(),
+			},
+		},
+	}
+
+	return operations, nil
+}
+
+// messageFromIntent completes the message described by the operations with the
+// nonce and gas values returned by /construction/metadata
+func messageFromIntent(intent *messageIntent, md map[string]interface{}) (*filTypes.Message, *types.Error) {
+	nonce, err := metadataUint64(md, NonceKey)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, err, false)
+	}
+
+	gasLimit, err := metadataUint64(md, GasLimitKey)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, err, false)
+	}
+
+	gasPremium, err := metadataBigInt(md, GasPremiumKey)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, err, false)
+	}
+
+	gasFeeCap, err := metadataBigInt(md, GasFeeCapKey)
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, err, false)
+	}
+
+	params := intent.params
+	if params == nil {
+		params = make([]byte, 0)
+	}
+
+	return &filTypes.Message{
+		Version:    filTypes.MessageVersion,
+		To:         intent.to,
+		From:       intent.from,
+		Nonce:      nonce,
+		Value:      intent.value,
+		GasLimit:   int64(gasLimit),
+		GasFeeCap:  gasFeeCap,
+		GasPremium: gasPremium,
+		Method:     intent.method,
+		Params:     params,
+	}, nil
+}
+
+// compactSignature converts a filecoin r || s || v secp256k1 signature to the
+// v || r || s layout used for public key recovery
+func compactSignature(sig []byte) []byte {
+	if len(sig) != ethSignatureLength {
+		return sig
+	}
+	return append([]byte{sig[ethSignatureLength-1] + 27}, sig[:ethSignatureLength-1]...)
+}
+
+// signingDigest returns the bytes a secp256k1 key has to sign for a message
+func signingDigest(message *filTypes.Message) []byte {
+	digest := blake2b.Sum256(message.Cid().Bytes())
+	return digest[:]
+}
+
+// payloadDigest returns the bytes the sender of a message has to sign on network
+func payloadDigest(network *types.NetworkIdentifier, message *filTypes.Message) ([]byte, error) {
+	if isDelegated(message.From) {
+		return delegatedSigningDigest(network, message)
+	}
+	return signingDigest(message), nil
+}
+
+// combineResponse serializes a signed message for /construction/combine
+func combineResponse(signedTx *filTypes.SignedMessage) (*types.ConstructionCombineResponse, *types.Error) {
+	signedTxJson, err := json.Marshal(signedTx)
+	if err != nil {
+		return nil, rosetta.BuildError(ErrMalformedTransaction, err, false)
+	}
+
+	resp := &types.ConstructionCombineResponse{
+		SignedTransaction: string(signedTxJson),
+	}
+
+	return resp, nil
+}
+
+// addressNetwork returns the address network matching the network this proxy serves
+func addressNetwork(network *types.NetworkIdentifier) address.Network {
+	if network.Network == mainnetName {
+		return address.Mainnet
+	}
+	return address.Testnet
+}
+
+// maxExactFloat is the largest integer a JSON number decoded as float64 holds exactly
+const maxExactFloat = 1 << 53
+
+// metadataInteger reads a required integer from a metadata map, accepting both
+// JSON numbers and decimal strings
+func metadataInteger(md map[string]interface{}, key string) (*big.Int, error) {
+	raw, ok := md[key]
+	if !ok {
+		return nil, fmt.Errorf("missing %s in metadata", key)
+	}
+
+	switch v := raw.(type) {
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case int64:
+		return big.NewInt(v), nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > maxExactFloat {
+			return nil, fmt.Errorf("%s must be an integer, use a string for large values", key)
+		}
+		return big.NewInt(int64(v)), nil
+	case json.Number:
+		parsed, ok := new(big.Int).SetString(v.String(), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s %s", key, v)
+		}
+		return parsed, nil
+	case string:
+		parsed, ok := new(big.Int).SetString(v, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s %s", key, v)
+		}
+		return parsed, nil
+	default:
+		return nil, fmt.Errorf("invalid type %T for %s", raw, key)
+	}
+}
+
+// metadataUint64 reads a required unsigned integer from a metadata map,
+// accepting both JSON numbers and decimal strings
+func metadataUint64(md map[string]interface{}, key string) (uint64, error) {
+	parsed, err := metadataInteger(md, key)
+	if err != nil {
+		return 0, err
+	}
+
+	if !parsed.IsUint64() {
+		return 0, fmt.Errorf("%s must be a non negative 64 bits integer", key)
+	}
+
+	return parsed.Uint64(), nil
+}
+
+// metadataBool reads a required boolean from a metadata map, accepting both
+// JSON booleans and strings
+func metadataBool(md map[string]interface{}, key string) (bool, error) {
+	switch v := md[key].(type) {
+	case bool:
+		return v, nil
+	case string:
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s %s", key, v)
+		}
+		return parsed, nil
+	default:
+		return false, fmt.Errorf("invalid type %T for %s", md[key], key)
+	}
+}
+
+// metadataBigInt reads a required token amount from a metadata map, accepting
+// both JSON numbers and decimal strings
+func metadataBigInt(md map[string]interface{}, key string) (abi.TokenAmount, error) {
+	parsed, err := metadataInteger(md, key)
+	if err != nil {
+		return abi.TokenAmount{}, err
+	}
+
+	return filTypes.BigInt{Int: parsed}, nil
+}
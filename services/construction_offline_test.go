@@ -0,0 +1,689 @@
+package services_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tests/fixtures"
+	filLib "github.com/zondax/rosetta-filecoin-lib"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+const testReceiver = "f1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba"
+
+var testNetwork = fixtures.Network
+
+// testCalibrationNetwork is a network other than mainnet
+var testCalibrationNetwork = &types.NetworkIdentifier{
+	Blockchain: rosetta.BlockChainName,
+	Network:    "calibrationnet",
+}
+
+func newOfflineConstructionService() *services.ConstructionAPIService {
+	return newOfflineConstructionServiceFor(testNetwork)
+}
+
+func newOfflineConstructionServiceFor(network *types.NetworkIdentifier) *services.ConstructionAPIService {
+	var node api.FullNode
+	return services.NewConstructionAPIService(network, &node, filLib.NewRosettaConstructionFilecoin(nil), services.ConstructionConfig{}).(*services.ConstructionAPIService)
+}
+
+func sendOperations(from, to, value string) []*types.Operation {
+	return []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 0},
+			Type:                services.SendOperationType,
+			Account:             &types.AccountIdentifier{Address: from},
+			Amount:              &types.Amount{Value: "-" + value, Currency: rosetta.GetCurrencyData()},
+		},
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 1},
+			Type:                services.SendOperationType,
+			Account:             &types.AccountIdentifier{Address: to},
+			Amount:              &types.Amount{Value: value, Currency: rosetta.GetCurrencyData()},
+		},
+	}
+}
+
+func unsignedMessageCid(t *testing.T, unsignedTx string) []byte {
+	t.Helper()
+
+	var message filTypes.Message
+	require.NoError(t, json.Unmarshal([]byte(unsignedTx), &message))
+	return message.Cid().Bytes()
+}
+
+func TestOfflineConstructionFlow(t *testing.T) {
+	ctx := context.Background()
+	c := newOfflineConstructionService()
+
+	privateKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	publicKey := &types.PublicKey{
+		Bytes:     privateKey.PubKey().SerializeCompressed(),
+		CurveType: types.Secp256k1,
+	}
+
+	derived, rosettaErr := c.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: testNetwork,
+		PublicKey:         publicKey,
+	})
+	require.Nil(t, rosettaErr)
+	sender := derived.AccountIdentifier.Address
+
+	operations := sendOperations(sender, testReceiver, "1000")
+	preprocessed, rosettaErr := c.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        operations,
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, sender, preprocessed.Options[services.OptionsSenderIDKey])
+	assert.Equal(t, testReceiver, preprocessed.Options[services.OptionsReceiverIDKey])
+	assert.Equal(t, "1000", preprocessed.Options[services.OptionsValueKey])
+
+	payloads, rosettaErr := c.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        operations,
+		Metadata: map[string]interface{}{
+			services.NonceKey:      float64(7),
+			services.GasLimitKey:   float64(600000),
+			services.GasPremiumKey: "100000",
+			services.GasFeeCapKey:  "200000",
+		},
+	})
+	require.Nil(t, rosettaErr)
+	require.Len(t, payloads.Payloads, 1)
+
+	parsed, rosettaErr := c.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: testNetwork,
+		Transaction:       payloads.UnsignedTransaction,
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, operations[0].Amount.Value, parsed.Operations[0].Amount.Value)
+	assert.Equal(t, testReceiver, parsed.Operations[1].Account.Address)
+
+	// SignRaw signs the blake2b digest of the message cid, which is what the payload holds
+	signature, err := filLib.NewRosettaConstructionFilecoin(nil).SignRaw(
+		unsignedMessageCid(t, payloads.UnsignedTransaction), privateKey.Serialize(),
+	)
+	require.NoError(t, err)
+
+	combined, rosettaErr := c.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   testNetwork,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				SigningPayload: payloads.Payloads[0],
+				PublicKey:      publicKey,
+				SignatureType:  types.EcdsaRecovery,
+				Bytes:          signature,
+			},
+		},
+	})
+	require.Nil(t, rosettaErr)
+
+	parsedSigned, rosettaErr := c.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: testNetwork,
+		Signed:            true,
+		Transaction:       combined.SignedTransaction,
+	})
+	require.Nil(t, rosettaErr)
+	require.Len(t, parsedSigned.AccountIdentifierSigners, 1)
+	assert.Equal(t, sender, parsedSigned.AccountIdentifierSigners[0].Address)
+
+	hash, rosettaErr := c.ConstructionHash(ctx, &types.ConstructionHashRequest{
+		NetworkIdentifier: testNetwork,
+		SignedTransaction: combined.SignedTransaction,
+	})
+	require.Nil(t, rosettaErr)
+	assert.NotEmpty(t, hash.TransactionIdentifier.Hash)
+}
+
+func TestDelegatedConstructionFlow(t *testing.T) {
+	ctx := context.Background()
+	c := newOfflineConstructionService()
+
+	privateKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	publicKey := &types.PublicKey{
+		Bytes:     privateKey.PubKey().SerializeCompressed(),
+		CurveType: types.Secp256k1,
+	}
+
+	derived, rosettaErr := c.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: testNetwork,
+		PublicKey:         publicKey,
+		Metadata:          map[string]interface{}{services.DeriveAddressTypeKey: services.DelegatedAddressType},
+	})
+	require.Nil(t, rosettaErr)
+	sender := derived.AccountIdentifier.Address
+	require.Equal(t, "f410", sender[:4])
+
+	operations := sendOperations(sender, "f01234", "1000")
+	payloads, rosettaErr := c.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        operations,
+		Metadata: map[string]interface{}{
+			services.NonceKey:      float64(2),
+			services.GasLimitKey:   float64(600000),
+			services.GasPremiumKey: "100000",
+			services.GasFeeCapKey:  "200000",
+		},
+	})
+	require.Nil(t, rosettaErr)
+	require.Len(t, payloads.Payloads, 1)
+
+	parsed, rosettaErr := c.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: testNetwork,
+		Transaction:       payloads.UnsignedTransaction,
+	})
+	require.Nil(t, rosettaErr)
+	require.Len(t, parsed.Operations, 2)
+	assert.Equal(t, "f01234", parsed.Operations[1].Account.Address)
+
+	// Convert the <v><r><s> compact signature to the <r><s><v> Ethereum layout
+	compact := ecdsa.SignCompact(privateKey, payloads.Payloads[0].Bytes, false)
+	signature := append(compact[1:], compact[0]-27)
+
+	combined, rosettaErr := c.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   testNetwork,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				SigningPayload: payloads.Payloads[0],
+				PublicKey:      publicKey,
+				SignatureType:  types.EcdsaRecovery,
+				Bytes:          signature,
+			},
+		},
+	})
+	require.Nil(t, rosettaErr)
+
+	hash, rosettaErr := c.ConstructionHash(ctx, &types.ConstructionHashRequest{
+		NetworkIdentifier: testNetwork,
+		SignedTransaction: combined.SignedTransaction,
+	})
+	require.Nil(t, rosettaErr)
+	assert.NotEmpty(t, hash.TransactionIdentifier.Hash)
+
+	// A signature by another key is rejected
+	otherKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	compact = ecdsa.SignCompact(otherKey, payloads.Payloads[0].Bytes, false)
+	_, rosettaErr = c.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   testNetwork,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				SigningPayload: payloads.Payloads[0],
+				SignatureType:  types.EcdsaRecovery,
+				Bytes:          append(compact[1:], compact[0]-27),
+			},
+		},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrInvalidSignature.Code, rosettaErr.Code)
+}
+
+// signedPayload builds a transfer from sender and signs it with privateKey
+func signedPayload(t *testing.T, c *services.ConstructionAPIService, sender string,
+	privateKey *secp256k1.PrivateKey) *types.ConstructionCombineRequest {
+	t.Helper()
+
+	payloads, rosettaErr := c.ConstructionPayloads(context.Background(), &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        sendOperations(sender, testReceiver, "1000"),
+		Metadata: map[string]interface{}{
+			services.NonceKey:      float64(1),
+			services.GasLimitKey:   float64(600000),
+			services.GasPremiumKey: "100000",
+			services.GasFeeCapKey:  "200000",
+		},
+	})
+	require.Nil(t, rosettaErr)
+
+	signature, err := filLib.NewRosettaConstructionFilecoin(nil).SignRaw(
+		unsignedMessageCid(t, payloads.UnsignedTransaction), privateKey.Serialize(),
+	)
+	require.NoError(t, err)
+
+	return &types.ConstructionCombineRequest{
+		NetworkIdentifier:   testNetwork,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				SigningPayload: payloads.Payloads[0],
+				PublicKey: &types.PublicKey{
+					Bytes:     privateKey.PubKey().SerializeCompressed(),
+					CurveType: types.Secp256k1,
+				},
+				SignatureType: types.EcdsaRecovery,
+				Bytes:         signature,
+			},
+		},
+	}
+}
+
+func TestConstructionCombineWrongKey(t *testing.T) {
+	ctx := context.Background()
+	c := newOfflineConstructionService()
+
+	senderKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	sender, err := address.NewSecp256k1Address(senderKey.PubKey().SerializeUncompressed())
+	require.NoError(t, err)
+	otherKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+
+	// The signature is valid for the given public key, which is not the sender's
+	_, rosettaErr := c.ConstructionCombine(ctx, signedPayload(t, c, sender.String(), otherKey))
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrInvalidSignature.Code, rosettaErr.Code)
+
+	_, rosettaErr = c.ConstructionCombine(ctx, signedPayload(t, c, sender.String(), senderKey))
+	require.Nil(t, rosettaErr)
+
+	// ID senders can only be resolved online
+	_, rosettaErr = c.ConstructionCombine(ctx, signedPayload(t, c, "f01001", senderKey))
+	assert.Equal(t, services.ErrUnavailableOffline, rosettaErr)
+}
+
+func TestConstructionCombineIDSender(t *testing.T) {
+	ctx := context.Background()
+	c, fullNodeMock := newOnlineConstructionService(t)
+
+	senderKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	sender, err := address.NewSecp256k1Address(senderKey.PubKey().SerializeUncompressed())
+	require.NoError(t, err)
+	senderID, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	fullNodeMock.On("StateAccountKey", mock.Anything, senderID, filTypes.EmptyTSK).Return(sender, nil)
+
+	combined, rosettaErr := c.ConstructionCombine(ctx, signedPayload(t, c, "f01001", senderKey))
+	require.Nil(t, rosettaErr)
+
+	// The hash resolves the ID sender the same way combine does
+	var signedTx filTypes.SignedMessage
+	require.NoError(t, json.Unmarshal([]byte(combined.SignedTransaction), &signedTx))
+	hash, rosettaErr := c.ConstructionHash(ctx, &types.ConstructionHashRequest{
+		NetworkIdentifier: testNetwork,
+		SignedTransaction: combined.SignedTransaction,
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, signedTx.Cid().String(), hash.TransactionIdentifier.Hash)
+
+	otherKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	_, rosettaErr = c.ConstructionCombine(ctx, signedPayload(t, c, "f01001", otherKey))
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrInvalidSignature.Code, rosettaErr.Code)
+}
+
+func TestConstructionZeroValueTransfer(t *testing.T) {
+	ctx := context.Background()
+	c := newOfflineConstructionService()
+
+	sender, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+
+	// The sender of a zero-value transfer is only told apart by the sign of its string
+	operations := sendOperations(sender.String(), testReceiver, "0")
+	preprocessed, rosettaErr := c.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        operations,
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, sender.String(), preprocessed.Options[services.OptionsSenderIDKey])
+	assert.Equal(t, testReceiver, preprocessed.Options[services.OptionsReceiverIDKey])
+	assert.Equal(t, "0", preprocessed.Options[services.OptionsValueKey])
+
+	_, rosettaErr = c.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        operations,
+		Metadata: map[string]interface{}{
+			services.NonceKey:      float64(1),
+			services.GasLimitKey:   float64(600000),
+			services.GasPremiumKey: "100000",
+			services.GasFeeCapKey:  "200000",
+		},
+	})
+	require.Nil(t, rosettaErr)
+}
+
+func TestOfflineConstructionNodeEndpoints(t *testing.T) {
+	ctx := context.Background()
+	c := newOfflineConstructionService()
+
+	_, rosettaErr := c.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{NetworkIdentifier: testNetwork})
+	assert.Equal(t, services.ErrUnavailableOffline, rosettaErr)
+
+	_, rosettaErr = c.ConstructionSubmit(ctx, &types.ConstructionSubmitRequest{NetworkIdentifier: testNetwork, SignedTransaction: "{}"})
+	assert.Equal(t, services.ErrUnavailableOffline, rosettaErr)
+}
+
+func TestConstructionPreprocessInvalidOperations(t *testing.T) {
+	ctx := context.Background()
+	c := newOfflineConstructionService()
+
+	operations := sendOperations(testReceiver, testReceiver, "10")
+	operations[1].Amount.Value = "11"
+
+	_, rosettaErr := c.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        operations,
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrInvalidOperations.Code, rosettaErr.Code)
+}
+
+func TestMultisigOperationsRoundTrip(t *testing.T) {
+	const (
+		multisigAddress = "f080"
+		signerAddress   = "f1abjxfbp274xpdqcpuaykwkfb43omjotacm2p3za"
+	)
+
+	tb := []struct {
+		name     string
+		opType   string
+		metadata map[string]interface{}
+	}{
+		{
+			name:   "propose transfer",
+			opType: services.ProposeOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMultisigKey: multisigAddress,
+				services.OperationToKey:       testReceiver,
+				services.OperationValueKey:    "1000",
+				services.OperationMethodKey:   uint64(0),
+			},
+		},
+		{
+			name:   "approve",
+			opType: services.ApproveOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMultisigKey:     multisigAddress,
+				services.OperationTxnIDKey:        uint64(3),
+				services.OperationProposalHashKey: "cHJvcG9zYWwgaGFzaA==",
+			},
+		},
+		{
+			name:   "cancel",
+			opType: services.CancelOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMultisigKey: multisigAddress,
+				services.OperationTxnIDKey:    uint64(4),
+			},
+		},
+		{
+			name:   "add signer",
+			opType: services.AddSignerOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMultisigKey: multisigAddress,
+				services.OperationSignerKey:   testReceiver,
+				services.OperationIncreaseKey: true,
+			},
+		},
+		{
+			name:   "remove signer",
+			opType: services.RemoveSignerOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMultisigKey: multisigAddress,
+				services.OperationSignerKey:   testReceiver,
+				services.OperationDecreaseKey: false,
+			},
+		},
+	}
+
+	for _, tt := range tb {
+		t.Run(tt.name, func(t *testing.T) {
+			assertOperationRoundTrip(t, tt.opType, signerAddress, tt.metadata)
+		})
+	}
+}
+
+func TestMinerOperationsRoundTrip(t *testing.T) {
+	const (
+		minerAddress = "f01000"
+		ownerAddress = "f1abjxfbp274xpdqcpuaykwkfb43omjotacm2p3za"
+	)
+
+	tb := []struct {
+		name     string
+		opType   string
+		metadata map[string]interface{}
+	}{
+		{
+			name:   "withdraw balance",
+			opType: services.WithdrawBalanceOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMinerKey:  minerAddress,
+				services.OperationAmountKey: "5000",
+			},
+		},
+		{
+			name:   "change owner",
+			opType: services.ChangeOwnerAddressOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMinerKey: minerAddress,
+				services.OperationOwnerKey: testReceiver,
+			},
+		},
+		{
+			name:   "change worker",
+			opType: services.ChangeWorkerAddressOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMinerKey:            minerAddress,
+				services.OperationWorkerKey:           "f01001",
+				services.OperationControlAddressesKey: []string{"f01002", testReceiver},
+			},
+		},
+		{
+			name:   "confirm change worker",
+			opType: services.ConfirmChangeWorkerAddressOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMinerKey: minerAddress,
+			},
+		},
+		{
+			name:   "change beneficiary",
+			opType: services.ChangeBeneficiaryOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMinerKey:       minerAddress,
+				services.OperationBeneficiaryKey: testReceiver,
+				services.OperationQuotaKey:       "1000000",
+				services.OperationExpirationKey:  uint64(4000000),
+			},
+		},
+		{
+			name:   "change peer id",
+			opType: services.ChangePeerIDOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMinerKey:  minerAddress,
+				services.OperationPeerIDKey: "12D3KooWGzxzKZYveHXtpG6AsrUJBcWxHBFS2HsEoGTxrMLvKXtf",
+			},
+		},
+		{
+			name:   "repay debt",
+			opType: services.RepayDebtOperationType,
+			metadata: map[string]interface{}{
+				services.OperationMinerKey: minerAddress,
+				services.OperationValueKey: "700",
+			},
+		},
+	}
+
+	for _, tt := range tb {
+		t.Run(tt.name, func(t *testing.T) {
+			assertOperationRoundTrip(t, tt.opType, ownerAddress, tt.metadata)
+		})
+	}
+}
+
+func TestMinerOperationsValidation(t *testing.T) {
+	ctx := context.Background()
+	c := newOfflineConstructionService()
+
+	_, rosettaErr := c.ConstructionPreprocess(ctx, &types.ConstructionPreprocessRequest{
+		NetworkIdentifier: testNetwork,
+		Operations: []*types.Operation{
+			{
+				OperationIdentifier: &types.OperationIdentifier{Index: 0},
+				Type:                services.WithdrawBalanceOperationType,
+				Account:             &types.AccountIdentifier{Address: testReceiver},
+				Metadata: map[string]interface{}{
+					services.OperationMinerKey:  "f01000",
+					services.OperationAmountKey: "0",
+				},
+			},
+		},
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrInvalidOperations.Code, rosettaErr.Code)
+}
+
+// assertOperationRoundTrip builds the message of a typed operation and checks
+// that parsing it gives the operation back
+func assertOperationRoundTrip(t *testing.T, opType, account string, metadata map[string]interface{}) {
+	t.Helper()
+
+	ctx := context.Background()
+	c := newOfflineConstructionService()
+
+	operations := []*types.Operation{
+		{
+			OperationIdentifier: &types.OperationIdentifier{Index: 0},
+			Type:                opType,
+			Account:             &types.AccountIdentifier{Address: account},
+			Metadata:            metadata,
+		},
+	}
+
+	payloads, rosettaErr := c.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: testNetwork,
+		Operations:        operations,
+		Metadata: map[string]interface{}{
+			services.NonceKey:      float64(1),
+			services.GasLimitKey:   float64(600000),
+			services.GasPremiumKey: "100000",
+			services.GasFeeCapKey:  "200000",
+		},
+	})
+	require.Nil(t, rosettaErr)
+
+	parsed, rosettaErr := c.ConstructionParse(ctx, &types.ConstructionParseRequest{
+		NetworkIdentifier: testNetwork,
+		Transaction:       payloads.UnsignedTransaction,
+	})
+	require.Nil(t, rosettaErr)
+	require.Len(t, parsed.Operations, 1)
+	assert.Equal(t, opType, parsed.Operations[0].Type)
+	assert.Equal(t, account, parsed.Operations[0].Account.Address)
+	assert.Equal(t, metadata, parsed.Operations[0].Metadata)
+}
+
+func TestConstructionParseAndHashEncodings(t *testing.T) {
+	ctx := context.Background()
+	c := newOfflineConstructionService()
+	signedJson := signedSendTransaction(t)
+
+	var signedTx filTypes.SignedMessage
+	require.NoError(t, json.Unmarshal([]byte(signedJson), &signedTx))
+	cborTx, err := signedTx.Serialize()
+	require.NoError(t, err)
+
+	// The same encodings as /construction/submit are accepted
+	for _, tx := range []string{
+		signedJson,
+		hex.EncodeToString(cborTx),
+		"0x" + hex.EncodeToString(cborTx),
+		base64.StdEncoding.EncodeToString(cborTx),
+	} {
+		parsed, rosettaErr := c.ConstructionParse(ctx, &types.ConstructionParseRequest{
+			NetworkIdentifier: testNetwork,
+			Signed:            true,
+			Transaction:       tx,
+		})
+		require.Nil(t, rosettaErr)
+		require.Len(t, parsed.AccountIdentifierSigners, 1)
+		assert.Equal(t, signedTx.Message.From.String(), parsed.AccountIdentifierSigners[0].Address)
+
+		hash, rosettaErr := c.ConstructionHash(ctx, &types.ConstructionHashRequest{
+			NetworkIdentifier: testNetwork,
+			SignedTransaction: tx,
+		})
+		require.Nil(t, rosettaErr)
+		assert.Equal(t, signedTx.Cid().String(), hash.TransactionIdentifier.Hash)
+	}
+}
+
+func TestDelegatedSignatureChainID(t *testing.T) {
+	ctx := context.Background()
+	mainnet := newOfflineConstructionService()
+	calibration := newOfflineConstructionServiceFor(testCalibrationNetwork)
+
+	privateKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	derived, rosettaErr := calibration.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: testCalibrationNetwork,
+		PublicKey: &types.PublicKey{
+			Bytes:     privateKey.PubKey().SerializeCompressed(),
+			CurveType: types.Secp256k1,
+		},
+		Metadata: map[string]interface{}{services.DeriveAddressTypeKey: services.DelegatedAddressType},
+	})
+	require.Nil(t, rosettaErr)
+
+	payloadsRequest := func(network *types.NetworkIdentifier) *types.ConstructionPayloadsRequest {
+		return &types.ConstructionPayloadsRequest{
+			NetworkIdentifier: network,
+			Operations:        sendOperations(derived.AccountIdentifier.Address, "f01234", "1000"),
+			Metadata: map[string]interface{}{
+				services.NonceKey:      float64(2),
+				services.GasLimitKey:   float64(600000),
+				services.GasPremiumKey: "100000",
+				services.GasFeeCapKey:  "200000",
+			},
+		}
+	}
+
+	// The chain id of the served network is part of the signed transaction
+	payloads, rosettaErr := calibration.ConstructionPayloads(ctx, payloadsRequest(testCalibrationNetwork))
+	require.Nil(t, rosettaErr)
+	mainnetPayloads, rosettaErr := mainnet.ConstructionPayloads(ctx, payloadsRequest(testNetwork))
+	require.Nil(t, rosettaErr)
+	assert.NotEqual(t, mainnetPayloads.Payloads[0].Bytes, payloads.Payloads[0].Bytes)
+
+	compact := ecdsa.SignCompact(privateKey, payloads.Payloads[0].Bytes, false)
+	combineRequest := &types.ConstructionCombineRequest{
+		NetworkIdentifier:   testCalibrationNetwork,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				SigningPayload: payloads.Payloads[0],
+				SignatureType:  types.EcdsaRecovery,
+				Bytes:          append(compact[1:], compact[0]-27),
+			},
+		},
+	}
+	_, rosettaErr = calibration.ConstructionCombine(ctx, combineRequest)
+	require.Nil(t, rosettaErr)
+
+	// The same signature does not hold on mainnet
+	_, rosettaErr = mainnet.ConstructionCombine(ctx, combineRequest)
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrInvalidSignature.Code, rosettaErr.Code)
+}
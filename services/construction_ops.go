@@ -0,0 +1,500 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/builtin"
+	"github.com/filecoin-project/go-state-types/builtin/v17/multisig"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+)
+
+const (
+	// ProposeOperationType is the type of the operation proposing a multisig transaction
+	ProposeOperationType = "Propose"
+
+	// ApproveOperationType is the type of the operation approving a pending multisig transaction
+	ApproveOperationType = "Approve"
+
+	// CancelOperationType is the type of the operation cancelling a pending multisig transaction
+	CancelOperationType = "Cancel"
+
+	// AddSignerOperationType is the type of the operation proposing to add a multisig signer
+	AddSignerOperationType = "AddSigner"
+
+	// RemoveSignerOperationType is the type of the operation proposing to remove a multisig signer
+	RemoveSignerOperationType = "RemoveSigner"
+)
+
+// OperationMultisigKey is the name of the key in the Metadata map of a multisig
+// operation that specifies the multisig actor address
+const OperationMultisigKey = "multisig"
+
+// OperationToKey is the name of the key in the Metadata map of a Propose
+// operation that specifies the receiver of the proposed transaction
+const OperationToKey = "to"
+
+// OperationValueKey is the name of the key in the Metadata map of a Propose
+// operation that specifies the tokens quantity of the proposed transaction
+const OperationValueKey = "value"
+
+// OperationMethodKey is the name of the key in the Metadata map of a Propose
+// operation that specifies the method num of the proposed transaction
+const OperationMethodKey = "method"
+
+// OperationParamsKey is the name of the key in the Metadata map of a Propose
+// operation that specifies the base64 encoded params of the proposed transaction
+const OperationParamsKey = "params"
+
+// OperationTxnIDKey is the name of the key in the Metadata map of an Approve or
+// Cancel operation that specifies the id of the pending transaction
+const OperationTxnIDKey = "txnId"
+
+// OperationProposalHashKey is the name of the key in the Metadata map of an Approve
+// or Cancel operation that specifies the base64 encoded hash of the proposal
+const OperationProposalHashKey = "proposalHash"
+
+// OperationSignerKey is the name of the key in the Metadata map of an AddSigner
+// or RemoveSigner operation that specifies the signer address
+const OperationSignerKey = "signer"
+
+// OperationIncreaseKey is the name of the key in the Metadata map of an AddSigner
+// operation that specifies whether the approvals threshold is increased
+const OperationIncreaseKey = "increase"
+
+// OperationDecreaseKey is the name of the key in the Metadata map of a RemoveSigner
+// operation that specifies whether the approvals threshold is decreased
+const OperationDecreaseKey = "decrease"
+
+// constructionOperation describes an operation type, other than Send, that the
+// construction endpoints can turn into a message and back.
+// A transaction built from one of these is described by a single operation whose
+// account is the sender and whose metadata holds the method arguments.
+type constructionOperation struct {
+	opType string
+	// build returns the message described by the operation metadata
+	build func(from address.Address, md map[string]interface{}) (*messageIntent, error)
+	// parse returns the operation metadata of a message, or false if the message
+	// was not built by this operation type
+	parse func(message *filTypes.Message) (map[string]interface{}, bool)
+}
+
+// constructionOperations are tried in order when parsing a message, more specific
+// types must come first.
+// Method numbers are only unique per actor, e.g. multisig Approve and miner
+// ChangeWorkerAddress are both method 3, they are told apart by their params.
+var constructionOperations = []constructionOperation{
+	{opType: AddSignerOperationType, build: buildAddSigner, parse: parseAddSigner},
+	{opType: RemoveSignerOperationType, build: buildRemoveSigner, parse: parseRemoveSigner},
+	{opType: ProposeOperationType, build: buildPropose, parse: parsePropose},
+	{opType: ApproveOperationType, build: buildApprove, parse: parseApprove},
+	{opType: CancelOperationType, build: buildCancel, parse: parseCancel},
+	{opType: InvokeContractOperationType, build: buildInvokeContract, parse: parseInvokeContract},
+	{opType: WithdrawBalanceOperationType, build: buildWithdrawBalance, parse: parseWithdrawBalance},
+	{opType: ChangeOwnerAddressOperationType, build: buildChangeOwnerAddress, parse: parseChangeOwnerAddress},
+	{opType: ChangeWorkerAddressOperationType, build: buildChangeWorkerAddress, parse: parseChangeWorkerAddress},
+	{opType: ConfirmChangeWorkerAddressOperationType, build: buildConfirmChangeWorkerAddress, parse: parseConfirmChangeWorkerAddress},
+	{opType: ChangeBeneficiaryOperationType, build: buildChangeBeneficiary, parse: parseChangeBeneficiary},
+	{opType: ChangePeerIDOperationType, build: buildChangePeerID, parse: parseChangePeerID},
+	{opType: RepayDebtOperationType, build: buildRepayDebt, parse: parseRepayDebt},
+}
+
+// SupportedOperations appends the operation types accepted by the construction
+// endpoints to ops, skipping the ones already present
+func SupportedOperations(ops []string) []string {
+	supported := append([]string{}, ops...)
+	seen := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		seen[op] = true
+	}
+
+	constructionTypes := []string{SendOperationType}
+	for _, op := range constructionOperations {
+		constructionTypes = append(constructionTypes, op.opType)
+	}
+
+	for _, op := range constructionTypes {
+		if !seen[op] {
+			supported = append(supported, op)
+			seen[op] = true
+		}
+	}
+
+	return supported
+}
+
+func findConstructionOperation(opType string) (constructionOperation, bool) {
+	for _, op := range constructionOperations {
+		if op.opType == opType {
+			return op, true
+		}
+	}
+	return constructionOperation{}, false
+}
+
+func buildPropose(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	msig, err := metadataAddress(md, OperationMultisigKey)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := metadataAddress(md, OperationToKey)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := metadataBigInt(md, OperationValueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var method uint64
+	if _, ok := md[OperationMethodKey]; ok {
+		method, err = metadataUint64(md, OperationMethodKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	innerParams, err := metadataOptionalBytes(md, OperationParamsKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return proposeIntent(from, msig, &multisig.ProposeParams{
+		To:     to,
+		Value:  value,
+		Method: abi.MethodNum(method),
+		Params: innerParams,
+	})
+}
+
+func parsePropose(message *filTypes.Message) (map[string]interface{}, bool) {
+	var params multisig.ProposeParams
+	if message.Method != builtin.MethodsMultisig.Propose || !decodeParams(message.Params, &params) {
+		return nil, false
+	}
+
+	md := map[string]interface{}{
+		OperationMultisigKey: message.To.String(),
+		OperationToKey:       params.To.String(),
+		OperationValueKey:    params.Value.String(),
+		OperationMethodKey:   uint64(params.Method),
+	}
+	if len(params.Params) > 0 {
+		md[OperationParamsKey] = base64.StdEncoding.EncodeToString(params.Params)
+	}
+
+	return md, true
+}
+
+func buildAddSigner(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	msig, err := metadataAddress(md, OperationMultisigKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := metadataAddress(md, OperationSignerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	increase, err := metadataOptionalBool(md, OperationIncreaseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := encodeParams(&multisig.AddSignerParams{Signer: signer, Increase: increase})
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the multisig can change its signers, so the change has to be proposed to itself
+	return proposeIntent(from, msig, &multisig.ProposeParams{
+		To:     msig,
+		Value:  big.Zero(),
+		Method: builtin.MethodsMultisig.AddSigner,
+		Params: params,
+	})
+}
+
+func parseAddSigner(message *filTypes.Message) (map[string]interface{}, bool) {
+	proposal, ok := selfProposal(message, builtin.MethodsMultisig.AddSigner)
+	if !ok {
+		return nil, false
+	}
+
+	var params multisig.AddSignerParams
+	if !decodeParams(proposal.Params, &params) {
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		OperationMultisigKey: message.To.String(),
+		OperationSignerKey:   params.Signer.String(),
+		OperationIncreaseKey: params.Increase,
+	}, true
+}
+
+func buildRemoveSigner(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	msig, err := metadataAddress(md, OperationMultisigKey)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := metadataAddress(md, OperationSignerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	decrease, err := metadataOptionalBool(md, OperationDecreaseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := encodeParams(&multisig.RemoveSignerParams{Signer: signer, Decrease: decrease})
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the multisig can change its signers, so the change has to be proposed to itself
+	return proposeIntent(from, msig, &multisig.ProposeParams{
+		To:     msig,
+		Value:  big.Zero(),
+		Method: builtin.MethodsMultisig.RemoveSigner,
+		Params: params,
+	})
+}
+
+func parseRemoveSigner(message *filTypes.Message) (map[string]interface{}, bool) {
+	proposal, ok := selfProposal(message, builtin.MethodsMultisig.RemoveSigner)
+	if !ok {
+		return nil, false
+	}
+
+	var params multisig.RemoveSignerParams
+	if !decodeParams(proposal.Params, &params) {
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		OperationMultisigKey: message.To.String(),
+		OperationSignerKey:   params.Signer.String(),
+		OperationDecreaseKey: params.Decrease,
+	}, true
+}
+
+func buildApprove(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	return txnIDIntent(from, md, builtin.MethodsMultisig.Approve)
+}
+
+func parseApprove(message *filTypes.Message) (map[string]interface{}, bool) {
+	return parseTxnID(message, builtin.MethodsMultisig.Approve)
+}
+
+func buildCancel(from address.Address, md map[string]interface{}) (*messageIntent, error) {
+	return txnIDIntent(from, md, builtin.MethodsMultisig.Cancel)
+}
+
+func parseCancel(message *filTypes.Message) (map[string]interface{}, bool) {
+	return parseTxnID(message, builtin.MethodsMultisig.Cancel)
+}
+
+// proposeIntent returns the message proposing a transaction to a multisig
+func proposeIntent(from, msig address.Address, proposal *multisig.ProposeParams) (*messageIntent, error) {
+	params, err := encodeParams(proposal)
+	if err != nil {
+		return nil, err
+	}
+
+	return &messageIntent{
+		from:   from,
+		to:     msig,
+		value:  big.Zero(),
+		method: builtin.MethodsMultisig.Propose,
+		params: params,
+	}, nil
+}
+
+// selfProposal returns the params of a message proposing to a multisig a call
+// of one of its own methods
+func selfProposal(message *filTypes.Message, method abi.MethodNum) (*multisig.ProposeParams, bool) {
+	var proposal multisig.ProposeParams
+	if message.Method != builtin.MethodsMultisig.Propose || !decodeParams(message.Params, &proposal) {
+		return nil, false
+	}
+
+	if proposal.To != message.To || proposal.Method != method {
+		return nil, false
+	}
+
+	return &proposal, true
+}
+
+// txnIDIntent returns the message approving or cancelling a pending multisig transaction
+func txnIDIntent(from address.Address, md map[string]interface{}, method abi.MethodNum) (*messageIntent, error) {
+	msig, err := metadataAddress(md, OperationMultisigKey)
+	if err != nil {
+		return nil, err
+	}
+
+	txnID, err := metadataUint64(md, OperationTxnIDKey)
+	if err != nil {
+		return nil, err
+	}
+
+	proposalHash, err := metadataOptionalBytes(md, OperationProposalHashKey)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := encodeParams(&multisig.TxnIDParams{
+		ID:           multisig.TxnID(txnID),
+		ProposalHash: proposalHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &messageIntent{
+		from:   from,
+		to:     msig,
+		value:  big.Zero(),
+		method: method,
+		params: params,
+	}, nil
+}
+
+func parseTxnID(message *filTypes.Message, method abi.MethodNum) (map[string]interface{}, bool) {
+	var params multisig.TxnIDParams
+	if message.Method != method || !decodeParams(message.Params, &params) || params.ID < 0 {
+		return nil, false
+	}
+
+	md := map[string]interface{}{
+		OperationMultisigKey: message.To.String(),
+		OperationTxnIDKey:    uint64(params.ID),
+	}
+	if len(params.ProposalHash) > 0 {
+		md[OperationProposalHashKey] = base64.StdEncoding.EncodeToString(params.ProposalHash)
+	}
+
+	return md, true
+}
+
+// intentFromOperation builds the message described by a single typed operation
+func intentFromOperation(op *types.Operation) (*messageIntent, error) {
+	constructionOp, ok := findConstructionOperation(op.Type)
+	if !ok {
+		return nil, fmt.Errorf("unsupported operation type %s", op.Type)
+	}
+
+	if op.Account == nil {
+		return nil, fmt.Errorf("operation %s has no account", op.Type)
+	}
+
+	from, err := address.NewFromString(op.Account.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return constructionOp.build(from, op.Metadata)
+}
+
+// operationFromMessage returns the typed operation describing a message, or
+// false if no operation type matches it
+func operationFromMessage(message *filTypes.Message) (*types.Operation, bool) {
+	for _, constructionOp := range constructionOperations {
+		md, ok := constructionOp.parse(message)
+		if !ok {
+			continue
+		}
+
+		return &types.Operation{
+			OperationIdentifier: &types.OperationIdentifier{Index: 0},
+			Type:                constructionOp.opType,
+			Account:             &types.AccountIdentifier{Address: message.From.String()},
+			Metadata:            md,
+		}, true
+	}
+
+	return nil, false
+}
+
+// cborMarshaler is implemented by the go-state-types method params
+type cborMarshaler interface {
+	MarshalCBOR(io.Writer) error
+}
+
+// encodeParams serializes method params to CBOR
+func encodeParams(params cborMarshaler) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := params.MarshalCBOR(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeParams deserializes CBOR method params, failing on trailing bytes so
+// that params of a different method are not mistaken for these
+func decodeParams(params []byte, v interface{ UnmarshalCBOR(io.Reader) error }) bool {
+	reader := bytes.NewReader(params)
+	if err := v.UnmarshalCBOR(reader); err != nil {
+		return false
+	}
+	return reader.Len() == 0
+}
+
+// metadataAddress reads a required address from a metadata map
+func metadataAddress(md map[string]interface{}, key string) (address.Address, error) {
+	raw, ok := md[key]
+	if !ok {
+		return address.Undef, fmt.Errorf("missing %s in metadata", key)
+	}
+
+	str, ok := raw.(string)
+	if !ok {
+		return address.Undef, fmt.Errorf("invalid type %T for %s", raw, key)
+	}
+	if str == "" {
+		return address.Undef, fmt.Errorf("empty %s in metadata", key)
+	}
+
+	return address.NewFromString(str)
+}
+
+// metadataOptionalBool reads an optional boolean from a metadata map
+func metadataOptionalBool(md map[string]interface{}, key string) (bool, error) {
+	raw, ok := md[key]
+	if !ok {
+		return false, nil
+	}
+
+	value, ok := raw.(bool)
+	if !ok {
+		return false, fmt.Errorf("invalid type %T for %s", raw, key)
+	}
+
+	return value, nil
+}
+
+// metadataOptionalBytes reads optional base64 encoded bytes from a metadata map
+func metadataOptionalBytes(md map[string]interface{}, key string) ([]byte, error) {
+	raw, ok := md[key]
+	if !ok {
+		return nil, nil
+	}
+
+	str, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid type %T for %s", raw, key)
+	}
+
+	return base64.StdEncoding.DecodeString(str)
+}
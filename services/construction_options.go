@@ -0,0 +1,163 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// InvalidOptionFieldKey is the name of the key in the Details map of an
+// ErrInvalidOption error that specifies the rejected option
+const InvalidOptionFieldKey = "field"
+
+// InvalidOptionReasonKey is the name of the key in the Details map of an
+// ErrInvalidOption error that specifies why the option was rejected
+const InvalidOptionReasonKey = "reason"
+
+// MetadataOptions are the typed options of a ConstructionMetadataRequest, as
+// returned by /construction/preprocess. Every field is optional.
+type MetadataOptions struct {
+	Sender    *address.Address
+	Receiver  *address.Address
+	Value     *abi.TokenAmount
+	MethodNum *abi.MethodNum
+	Params    []byte
+	// BlockIncl defaults to 1, which is also what 0 is read as
+	BlockIncl uint64
+	// MaxFee defaults to DefaultMaxFee
+	MaxFee abi.TokenAmount
+	// FeeTiers asks for the fee tiers in the response
+	FeeTiers bool
+}
+
+// metadataOptionKeys are the keys accepted in the options of a ConstructionMetadataRequest
+var metadataOptionKeys = map[string]bool{
+	OptionsSenderIDKey:   true,
+	OptionsReceiverIDKey: true,
+	OptionsValueKey:      true,
+	OptionsMethodNumKey:  true,
+	OptionsParamsKey:     true,
+	OptionsBlockInclKey:  true,
+	OptionsMaxFeeKey:     true,
+	OptionsFeeTiersKey:   true,
+}
+
+// ParseMetadataOptions validates the options of a ConstructionMetadataRequest.
+// Numbers are accepted both as JSON numbers and decimal strings. In strict mode
+// unknown keys are rejected.
+func ParseMetadataOptions(options map[string]interface{}, strict bool) (*MetadataOptions, *types.Error) {
+	opts := &MetadataOptions{
+		BlockIncl: 1,
+		MaxFee:    DefaultMaxFee,
+	}
+
+	if strict {
+		// Sorted so the same request always reports the same field
+		keys := make([]string, 0, len(options))
+		for key := range options {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if !metadataOptionKeys[key] {
+				return nil, invalidOption(ErrInvalidOption, key, fmt.Errorf("unknown option"))
+			}
+		}
+	}
+
+	if _, ok := options[OptionsSenderIDKey]; ok {
+		sender, err := metadataAddress(options, OptionsSenderIDKey)
+		if err != nil {
+			return nil, invalidOption(ErrInvalidOption, OptionsSenderIDKey, err)
+		}
+		opts.Sender = &sender
+	}
+
+	if _, ok := options[OptionsReceiverIDKey]; ok {
+		receiver, err := metadataAddress(options, OptionsReceiverIDKey)
+		if err != nil {
+			return nil, invalidOption(ErrInvalidOption, OptionsReceiverIDKey, err)
+		}
+		opts.Receiver = &receiver
+	}
+
+	if _, ok := options[OptionsValueKey]; ok {
+		value, err := metadataBigInt(options, OptionsValueKey)
+		if err == nil && value.Sign() < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+		if err != nil {
+			return nil, invalidOption(ErrInvalidOption, OptionsValueKey, err)
+		}
+		opts.Value = &value
+	}
+
+	if _, ok := options[OptionsMethodNumKey]; ok {
+		methodNum, err := metadataUint64(options, OptionsMethodNumKey)
+		if err != nil {
+			return nil, invalidOption(ErrInvalidOption, OptionsMethodNumKey, err)
+		}
+		method := abi.MethodNum(methodNum)
+		opts.MethodNum = &method
+	}
+
+	if rawParams, ok := options[OptionsParamsKey]; ok {
+		params, ok := rawParams.(string)
+		if !ok {
+			return nil, invalidOption(ErrMalformedParams, OptionsParamsKey, fmt.Errorf("invalid type %T", rawParams))
+		}
+		decoded, err := base64.StdEncoding.DecodeString(params)
+		if err != nil {
+			return nil, invalidOption(ErrMalformedParams, OptionsParamsKey, err)
+		}
+		opts.Params = decoded
+	}
+
+	if _, ok := options[OptionsBlockInclKey]; ok {
+		blockIncl, err := metadataUint64(options, OptionsBlockInclKey)
+		if err != nil {
+			return nil, invalidOption(ErrInvalidOption, OptionsBlockInclKey, err)
+		}
+		// Lotus treats 0 as the next epoch
+		if blockIncl > 0 {
+			opts.BlockIncl = blockIncl
+		}
+	}
+
+	if _, ok := options[OptionsMaxFeeKey]; ok {
+		maxFee, err := metadataBigInt(options, OptionsMaxFeeKey)
+		if err == nil && maxFee.Sign() <= 0 {
+			err = fmt.Errorf("must be a positive amount")
+		}
+		if err != nil {
+			return nil, invalidOption(ErrInvalidOption, OptionsMaxFeeKey, err)
+		}
+		opts.MaxFee = maxFee
+	}
+
+	if _, ok := options[OptionsFeeTiersKey]; ok {
+		feeTiers, err := metadataBool(options, OptionsFeeTiersKey)
+		if err != nil {
+			return nil, invalidOption(ErrInvalidOption, OptionsFeeTiersKey, err)
+		}
+		opts.FeeTiers = feeTiers
+	}
+
+	return opts, nil
+}
+
+// invalidOption returns a copy of base naming the rejected option in its details
+func invalidOption(base *types.Error, field string, reason error) *types.Error {
+	optionErr := *base
+	optionErr.Description = types.String(fmt.Sprintf("invalid option %s: %v", field, reason))
+	optionErr.Details = map[string]interface{}{
+		InvalidOptionFieldKey:  field,
+		InvalidOptionReasonKey: reason.Error(),
+	}
+	return &optionErr
+}
@@ -0,0 +1,158 @@
+package services_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+)
+
+func TestParseMetadataOptions(t *testing.T) {
+	tb := []struct {
+		name    string
+		options map[string]interface{}
+		strict  bool
+		field   string
+		errCode int32
+	}{
+		{
+			name: "numbers as JSON numbers",
+			options: map[string]interface{}{
+				services.OptionsSenderIDKey:  testReceiver,
+				services.OptionsMethodNumKey: float64(2),
+				services.OptionsBlockInclKey: float64(3),
+				services.OptionsValueKey:     float64(10),
+			},
+		},
+		{
+			name: "numbers as strings",
+			options: map[string]interface{}{
+				services.OptionsMethodNumKey: "2",
+				services.OptionsBlockInclKey: "3",
+				services.OptionsValueKey:     "10",
+				services.OptionsMaxFeeKey:    "1000000000000000000000",
+				services.OptionsFeeTiersKey:  "true",
+			},
+		},
+		{
+			name:    "fee tiers not a boolean",
+			options: map[string]interface{}{services.OptionsFeeTiersKey: float64(1)},
+			field:   services.OptionsFeeTiersKey,
+			errCode: services.ErrInvalidOption.Code,
+		},
+		{
+			name:    "fractional number",
+			options: map[string]interface{}{services.OptionsBlockInclKey: 1.5},
+			field:   services.OptionsBlockInclKey,
+			errCode: services.ErrInvalidOption.Code,
+		},
+		{
+			name:    "negative method",
+			options: map[string]interface{}{services.OptionsMethodNumKey: "-1"},
+			field:   services.OptionsMethodNumKey,
+			errCode: services.ErrInvalidOption.Code,
+		},
+		{
+			name:    "address with wrong type",
+			options: map[string]interface{}{services.OptionsSenderIDKey: float64(1)},
+			field:   services.OptionsSenderIDKey,
+			errCode: services.ErrInvalidOption.Code,
+		},
+		{
+			name:    "params not base64",
+			options: map[string]interface{}{services.OptionsParamsKey: "not base64!"},
+			field:   services.OptionsParamsKey,
+			errCode: services.ErrMalformedParams.Code,
+		},
+		{
+			name:    "zero max fee",
+			options: map[string]interface{}{services.OptionsMaxFeeKey: "0"},
+			field:   services.OptionsMaxFeeKey,
+			errCode: services.ErrInvalidOption.Code,
+		},
+		{
+			name:    "unknown key accepted",
+			options: map[string]interface{}{"foo": "bar"},
+		},
+		{
+			name:    "unknown key in strict mode",
+			options: map[string]interface{}{"foo": "bar"},
+			strict:  true,
+			field:   "foo",
+			errCode: services.ErrInvalidOption.Code,
+		},
+	}
+
+	for _, tt := range tb {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, rosettaErr := services.ParseMetadataOptions(tt.options, tt.strict)
+			if tt.field == "" {
+				require.Nil(t, rosettaErr)
+				require.NotNil(t, opts)
+				return
+			}
+
+			require.NotNil(t, rosettaErr)
+			assert.Equal(t, tt.errCode, rosettaErr.Code)
+			assert.Equal(t, tt.field, rosettaErr.Details[services.InvalidOptionFieldKey])
+		})
+	}
+}
+
+func TestParseMetadataOptionsValues(t *testing.T) {
+	opts, rosettaErr := services.ParseMetadataOptions(map[string]interface{}{
+		services.OptionsReceiverIDKey: testReceiver,
+		services.OptionsMethodNumKey:  float64(2),
+		services.OptionsParamsKey:     "AQI=",
+	}, true)
+	require.Nil(t, rosettaErr)
+
+	assert.Nil(t, opts.Sender)
+	assert.Equal(t, testReceiver, opts.Receiver.String())
+	assert.Equal(t, abi.MethodNum(2), *opts.MethodNum)
+	assert.Equal(t, []byte{1, 2}, opts.Params)
+	assert.Equal(t, uint64(1), opts.BlockIncl)
+	assert.Equal(t, services.DefaultMaxFee, opts.MaxFee)
+	assert.False(t, opts.FeeTiers)
+}
+
+func TestParseMetadataOptionsZeroBlockIncl(t *testing.T) {
+	// 0 asks for the fastest inclusion, which is the next epoch
+	opts, rosettaErr := services.ParseMetadataOptions(map[string]interface{}{
+		services.OptionsBlockInclKey: float64(0),
+	}, true)
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, uint64(1), opts.BlockIncl)
+}
+
+func FuzzParseMetadataOptions(f *testing.F) {
+	f.Add([]byte(`{"idSender":"f1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba","value":"10","blockIncl":3}`), false)
+	f.Add([]byte(`{"methodNum":"18446744073709551616","params":"AQI="}`), true)
+	f.Add([]byte(`{"blockIncl":1e300,"maxFee":-1,"idReceiver":null}`), false)
+	f.Add([]byte(`{"value":[1],"params":{},"unknown":true}`), true)
+	f.Add([]byte(`{"":""}`), true)
+
+	f.Fuzz(func(t *testing.T, data []byte, strict bool) {
+		var options map[string]interface{}
+		if err := json.Unmarshal(data, &options); err != nil {
+			return
+		}
+
+		opts, rosettaErr := services.ParseMetadataOptions(options, strict)
+		if rosettaErr != nil {
+			require.Nil(t, opts)
+			assert.Contains(t, rosettaErr.Details, services.InvalidOptionFieldKey)
+			return
+		}
+
+		require.NotNil(t, opts)
+		assert.GreaterOrEqual(t, opts.BlockIncl, uint64(1))
+		assert.GreaterOrEqual(t, opts.MaxFee.Sign(), 0)
+		if opts.Value != nil {
+			assert.GreaterOrEqual(t, opts.Value.Sign(), 0)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+const (
+	// PreflightExitCodeKey is the name of the key in the Details map of an
+	// ErrPreflightFailed error that specifies the simulation exit code
+	PreflightExitCodeKey = "exitCode"
+
+	// PreflightErrorKey is the name of the key in the Details map of an
+	// ErrPreflightFailed error that specifies the simulation error
+	PreflightErrorKey = "error"
+
+	// PreflightReturnKey is the name of the key in the Details map of an
+	// ErrPreflightFailed error that specifies the 0x prefixed hex return value
+	PreflightReturnKey = "return"
+
+	// PreflightRevertReasonKey is the name of the key in the Details map of an
+	// ErrPreflightFailed error that specifies the reason string of a reverted EVM call
+	PreflightRevertReasonKey = "revertReason"
+)
+
+// solidityErrorSelector is the selector of the Error(string) revert data
+var solidityErrorSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+// preflight runs a signed message against the head tipset and refuses it if it
+// would not exit successfully
+func (c *ConstructionAPIService) preflight(ctx context.Context, signedTx *filTypes.SignedMessage) *types.Error {
+	var (
+		result *api.InvocResult
+		err    error
+	)
+
+	impl := func() {
+		result, err = c.node.StateCall(ctx, &signedTx.Message, filTypes.EmptyTSK)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, LotusCallTimeOut)
+	if errTimeOut != nil {
+		return rosetta.ErrLotusCallTimedOut
+	}
+
+	if err != nil {
+		return rosetta.BuildError(ErrUnableToSimulateTx, err, true)
+	}
+
+	if result.MsgRct == nil {
+		return rosetta.BuildError(ErrUnableToSimulateTx, fmt.Errorf("simulation returned no receipt"), true)
+	}
+
+	if result.MsgRct.ExitCode.IsSuccess() {
+		return nil
+	}
+
+	details := map[string]interface{}{
+		PreflightExitCodeKey: int64(result.MsgRct.ExitCode),
+		PreflightErrorKey:    result.Error,
+	}
+
+	ret := DecodeReturn(result.MsgRct.Return)
+	if len(ret) > 0 {
+		details[PreflightReturnKey] = "0x" + hex.EncodeToString(ret)
+		if reason, ok := DecodeRevertReason(ret); ok {
+			details[PreflightRevertReasonKey] = reason
+		}
+	}
+
+	preflightErr := *ErrPreflightFailed
+	preflightErr.Description = types.String(fmt.Sprintf("message would exit with code %d", result.MsgRct.ExitCode))
+	preflightErr.Details = details
+	return &preflightErr
+}
+
+// DecodeReturn unwraps return values encoded as a CBOR byte string, like the
+// ones of EVM calls, and leaves any other value untouched
+func DecodeReturn(ret []byte) []byte {
+	if len(ret) == 0 {
+		return nil
+	}
+
+	var unwrapped abi.CborBytes
+	reader := bytes.NewReader(ret)
+	if err := unwrapped.UnmarshalCBOR(reader); err != nil || reader.Len() > 0 {
+		return ret
+	}
+
+	return unwrapped
+}
+
+// DecodeRevertReason decodes the string of a Solidity Error(string) revert
+func DecodeRevertReason(ret []byte) (string, bool) {
+	// selector + offset word + length word
+	const headerLength = 4 + 32 + 32
+	if len(ret) < headerLength || !bytes.Equal(ret[:4], solidityErrorSelector) {
+		return "", false
+	}
+
+	lengthWord := ret[4+32 : headerLength]
+	if !bytes.Equal(lengthWord[:24], make([]byte, 24)) {
+		return "", false
+	}
+
+	length := binary.BigEndian.Uint64(lengthWord[24:])
+	if length > uint64(len(ret)-headerLength) {
+		return "", false
+	}
+
+	return string(ret[headerLength : headerLength+int(length)]), true
+}
@@ -0,0 +1,405 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tests/fixtures"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tests/mocks"
+	filLib "github.com/zondax/rosetta-filecoin-lib"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+// signedSendTransaction builds and signs a transfer with the offline endpoints
+func signedSendTransaction(t *testing.T) string {
+	t.Helper()
+	return signedSendTransactionFor(t, testNetwork)
+}
+
+// signedSendTransactionFor builds and signs a transfer on network with the offline endpoints
+func signedSendTransactionFor(t *testing.T, network *types.NetworkIdentifier) string {
+	t.Helper()
+
+	ctx := context.Background()
+	c := newOfflineConstructionServiceFor(network)
+
+	privateKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	publicKey := &types.PublicKey{
+		Bytes:     privateKey.PubKey().SerializeCompressed(),
+		CurveType: types.Secp256k1,
+	}
+
+	derived, rosettaErr := c.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: network,
+		PublicKey:         publicKey,
+	})
+	require.Nil(t, rosettaErr)
+
+	payloads, rosettaErr := c.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: network,
+		Operations:        sendOperations(derived.AccountIdentifier.Address, testReceiver, "1000"),
+		Metadata: map[string]interface{}{
+			services.NonceKey:      float64(1),
+			services.GasLimitKey:   float64(600000),
+			services.GasPremiumKey: "100000",
+			services.GasFeeCapKey:  "200000",
+		},
+	})
+	require.Nil(t, rosettaErr)
+
+	signature, err := filLib.NewRosettaConstructionFilecoin(nil).SignRaw(
+		unsignedMessageCid(t, payloads.UnsignedTransaction), privateKey.Serialize(),
+	)
+	require.NoError(t, err)
+
+	combined, rosettaErr := c.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   testNetwork,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				SigningPayload: payloads.Payloads[0],
+				PublicKey:      publicKey,
+				SignatureType:  types.EcdsaRecovery,
+				Bytes:          signature,
+			},
+		},
+	})
+	require.Nil(t, rosettaErr)
+
+	return combined.SignedTransaction
+}
+
+// newOnlineCalibrationService returns an online construction service for a
+// calibration node
+func newOnlineCalibrationService(t *testing.T) (*services.ConstructionAPIService, *mocks.FullNode) {
+	fullNodeMock := mocks.NewFullNode(t)
+	fullNodeMock.On("StateNetworkName", mock.Anything).Return(dtypes.NetworkName(testCalibrationNetwork.Network), nil).Maybe()
+
+	var node api.FullNode = fullNodeMock
+	c := services.NewConstructionAPIService(testCalibrationNetwork, &node, filLib.NewRosettaConstructionFilecoin(nil), services.ConstructionConfig{}).(*services.ConstructionAPIService)
+	return c, fullNodeMock
+}
+
+func newOnlineConstructionService(t *testing.T) (*services.ConstructionAPIService, *mocks.FullNode) {
+	return newConfiguredConstructionService(t, services.ConstructionConfig{})
+}
+
+func newConfiguredConstructionService(t *testing.T, config services.ConstructionConfig) (*services.ConstructionAPIService, *mocks.FullNode) {
+	fullNodeMock := fixtures.NewFullNode(t)
+
+	var node api.FullNode = fullNodeMock
+	c := services.NewConstructionAPIService(testNetwork, &node, filLib.NewRosettaConstructionFilecoin(nil), config).(*services.ConstructionAPIService)
+	return c, fullNodeMock
+}
+
+func TestConstructionSubmitEncodings(t *testing.T) {
+	signedJson := signedSendTransaction(t)
+
+	var signedTx filTypes.SignedMessage
+	require.NoError(t, json.Unmarshal([]byte(signedJson), &signedTx))
+	cborTx, err := signedTx.Serialize()
+	require.NoError(t, err)
+
+	tb := []struct {
+		name string
+		tx   string
+	}{
+		{name: "json", tx: signedJson},
+		{name: "hex", tx: hex.EncodeToString(cborTx)},
+		{name: "prefixed hex", tx: "0x" + hex.EncodeToString(cborTx)},
+		{name: "base64", tx: base64.StdEncoding.EncodeToString(cborTx)},
+	}
+
+	for _, tt := range tb {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fullNodeMock := newOnlineConstructionService(t)
+			fullNodeMock.On("MpoolPush", mock.Anything, mock.MatchedBy(func(sm *filTypes.SignedMessage) bool {
+				return sm.Cid() == signedTx.Cid()
+			})).Return(signedTx.Cid(), nil)
+
+			resp, rosettaErr := c.ConstructionSubmit(context.Background(), &types.ConstructionSubmitRequest{
+				NetworkIdentifier: testNetwork,
+				SignedTransaction: tt.tx,
+			})
+			require.Nil(t, rosettaErr)
+			assert.Equal(t, signedTx.Cid().String(), resp.TransactionIdentifier.Hash)
+		})
+	}
+}
+
+func TestConstructionSubmitValidation(t *testing.T) {
+	signedJson := signedSendTransaction(t)
+
+	tb := []struct {
+		name    string
+		tx      string
+		errCode int32
+	}{
+		{
+			name:    "not encoded",
+			tx:      "not a transaction",
+			errCode: services.ErrMalformedTransaction.Code,
+		},
+		{
+			// Both valid hex and base64, neither holding a signed message
+			name:    "ambiguous encoding",
+			tx:      "deadbeef",
+			errCode: services.ErrMalformedTransaction.Code,
+		},
+		{
+			name:    "network mismatch",
+			tx:      strings.Replace(signedJson, `"To":"f1`, `"To":"t1`, 1),
+			errCode: services.ErrNetworkMismatch.Code,
+		},
+		{
+			name:    "invalid address",
+			tx:      strings.Replace(signedJson, `"To":"f1`, `"To":"f9`, 1),
+			errCode: rosetta.ErrInvalidAccountAddress.Code,
+		},
+		{
+			name:    "bad signature type",
+			tx:      strings.Replace(signedJson, `"Type":1`, `"Type":2`, 1),
+			errCode: services.ErrInvalidSignatureType.Code,
+		},
+	}
+
+	for _, tt := range tb {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newOnlineConstructionService(t)
+
+			_, rosettaErr := c.ConstructionSubmit(context.Background(), &types.ConstructionSubmitRequest{
+				NetworkIdentifier: testNetwork,
+				SignedTransaction: tt.tx,
+			})
+			require.NotNil(t, rosettaErr)
+			assert.Equal(t, tt.errCode, rosettaErr.Code)
+		})
+	}
+}
+
+// CBOR addresses have no network prefix, so only JSON messages can be checked
+// against the network
+func TestConstructionSubmitCBORNetwork(t *testing.T) {
+	testnetJson := strings.Replace(signedSendTransaction(t), `"To":"f1`, `"To":"t1`, 1)
+
+	var signedTx filTypes.SignedMessage
+	require.NoError(t, json.Unmarshal([]byte(testnetJson), &signedTx))
+	cborTx, err := signedTx.Serialize()
+	require.NoError(t, err)
+
+	c, fullNodeMock := newOnlineConstructionService(t)
+	fullNodeMock.On("MpoolPush", mock.Anything, mock.Anything).Return(signedTx.Cid(), nil)
+
+	_, rosettaErr := c.ConstructionSubmit(context.Background(), &types.ConstructionSubmitRequest{
+		NetworkIdentifier: testNetwork,
+		SignedTransaction: testnetJson,
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrNetworkMismatch.Code, rosettaErr.Code)
+
+	resp, rosettaErr := c.ConstructionSubmit(context.Background(), &types.ConstructionSubmitRequest{
+		NetworkIdentifier: testNetwork,
+		SignedTransaction: hex.EncodeToString(cborTx),
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, signedTx.Cid().String(), resp.TransactionIdentifier.Hash)
+}
+
+// The combine output encodes addresses for the build's network, which is not the
+// served one on calibration
+func TestConstructionSubmitCombinedOnCalibration(t *testing.T) {
+	signedJson := signedSendTransactionFor(t, testCalibrationNetwork)
+
+	var signedTx filTypes.SignedMessage
+	require.NoError(t, json.Unmarshal([]byte(signedJson), &signedTx))
+
+	c, fullNodeMock := newOnlineCalibrationService(t)
+	fullNodeMock.On("MpoolPush", mock.Anything, mock.Anything).Return(signedTx.Cid(), nil)
+
+	_, rosettaErr := c.ConstructionParse(context.Background(), &types.ConstructionParseRequest{
+		NetworkIdentifier: testCalibrationNetwork,
+		Signed:            true,
+		Transaction:       signedJson,
+	})
+	require.Nil(t, rosettaErr)
+
+	resp, rosettaErr := c.ConstructionSubmit(context.Background(), &types.ConstructionSubmitRequest{
+		NetworkIdentifier: testCalibrationNetwork,
+		SignedTransaction: signedJson,
+	})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, signedTx.Cid().String(), resp.TransactionIdentifier.Hash)
+}
+
+// Delegated senders sign the chain id, which reveals the network of CBOR messages
+func TestConstructionSubmitCBORDelegatedNetworkMismatch(t *testing.T) {
+	ctx := context.Background()
+	calibration := newOfflineConstructionServiceFor(testCalibrationNetwork)
+
+	privateKey, err := secp256k1.GeneratePrivateKey()
+	require.NoError(t, err)
+	derived, rosettaErr := calibration.ConstructionDerive(ctx, &types.ConstructionDeriveRequest{
+		NetworkIdentifier: testCalibrationNetwork,
+		PublicKey: &types.PublicKey{
+			Bytes:     privateKey.PubKey().SerializeCompressed(),
+			CurveType: types.Secp256k1,
+		},
+		Metadata: map[string]interface{}{services.DeriveAddressTypeKey: services.DelegatedAddressType},
+	})
+	require.Nil(t, rosettaErr)
+
+	payloads, rosettaErr := calibration.ConstructionPayloads(ctx, &types.ConstructionPayloadsRequest{
+		NetworkIdentifier: testCalibrationNetwork,
+		Operations:        sendOperations(derived.AccountIdentifier.Address, "f01234", "1000"),
+		Metadata: map[string]interface{}{
+			services.NonceKey:      float64(2),
+			services.GasLimitKey:   float64(600000),
+			services.GasPremiumKey: "100000",
+			services.GasFeeCapKey:  "200000",
+		},
+	})
+	require.Nil(t, rosettaErr)
+
+	compact := ecdsa.SignCompact(privateKey, payloads.Payloads[0].Bytes, false)
+	combined, rosettaErr := calibration.ConstructionCombine(ctx, &types.ConstructionCombineRequest{
+		NetworkIdentifier:   testCalibrationNetwork,
+		UnsignedTransaction: payloads.UnsignedTransaction,
+		Signatures: []*types.Signature{
+			{
+				SigningPayload: payloads.Payloads[0],
+				SignatureType:  types.EcdsaRecovery,
+				Bytes:          append(compact[1:], compact[0]-27),
+			},
+		},
+	})
+	require.Nil(t, rosettaErr)
+
+	var signedTx filTypes.SignedMessage
+	require.NoError(t, json.Unmarshal([]byte(combined.SignedTransaction), &signedTx))
+	cborTx, err := signedTx.Serialize()
+	require.NoError(t, err)
+
+	c, _ := newOnlineConstructionService(t)
+	_, rosettaErr = c.ConstructionSubmit(ctx, &types.ConstructionSubmitRequest{
+		NetworkIdentifier: testNetwork,
+		SignedTransaction: hex.EncodeToString(cborTx),
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, services.ErrNetworkMismatch.Code, rosettaErr.Code)
+}
+
+func TestConstructionSubmitPreflight(t *testing.T) {
+	signedJson := signedSendTransaction(t)
+
+	// Error(string) revert data with the reason "nope", wrapped in a CBOR byte string
+	revertData, err := hex.DecodeString("08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"6e6f706500000000000000000000000000000000000000000000000000000000")
+	require.NoError(t, err)
+	var cborReturn bytes.Buffer
+	wrapped := abi.CborBytes(revertData)
+	require.NoError(t, wrapped.MarshalCBOR(&cborReturn))
+
+	t.Run("refuses failing messages", func(t *testing.T) {
+		c, fullNodeMock := newConfiguredConstructionService(t, services.ConstructionConfig{PreflightSimulation: true})
+		fullNodeMock.On("StateCall", mock.Anything, mock.Anything, filTypes.EmptyTSK).Return(&api.InvocResult{
+			MsgRct: &filTypes.MessageReceipt{ExitCode: exitcode.ExitCode(33), Return: cborReturn.Bytes()},
+			Error:  "contract reverted",
+		}, nil)
+
+		_, rosettaErr := c.ConstructionSubmit(context.Background(), &types.ConstructionSubmitRequest{
+			NetworkIdentifier: testNetwork,
+			SignedTransaction: signedJson,
+		})
+		require.NotNil(t, rosettaErr)
+		assert.Equal(t, services.ErrPreflightFailed.Code, rosettaErr.Code)
+		assert.Equal(t, int64(33), rosettaErr.Details[services.PreflightExitCodeKey])
+		assert.Equal(t, "contract reverted", rosettaErr.Details[services.PreflightErrorKey])
+		assert.Equal(t, "0x"+hex.EncodeToString(revertData), rosettaErr.Details[services.PreflightReturnKey])
+		assert.Equal(t, "nope", rosettaErr.Details[services.PreflightRevertReasonKey])
+		assert.Nil(t, services.ErrPreflightFailed.Details)
+	})
+
+	t.Run("pushes successful messages", func(t *testing.T) {
+		c, fullNodeMock := newConfiguredConstructionService(t, services.ConstructionConfig{PreflightSimulation: true})
+		fullNodeMock.On("StateCall", mock.Anything, mock.Anything, filTypes.EmptyTSK).Return(&api.InvocResult{
+			MsgRct: &filTypes.MessageReceipt{ExitCode: exitcode.Ok},
+		}, nil)
+		fullNodeMock.On("MpoolPush", mock.Anything, mock.Anything).Return(cid.Undef, nil)
+
+		_, rosettaErr := c.ConstructionSubmit(context.Background(), &types.ConstructionSubmitRequest{
+			NetworkIdentifier: testNetwork,
+			SignedTransaction: signedJson,
+		})
+		require.Nil(t, rosettaErr)
+	})
+}
+
+func TestConstructionSubmitFailureReleasesNonce(t *testing.T) {
+	ctx := context.Background()
+	signed := signedSendTransaction(t)
+	var signedTx filTypes.SignedMessage
+	require.NoError(t, json.Unmarshal([]byte(signed), &signedTx))
+	sender := signedTx.Message.From
+
+	c, fullNodeMock := newConfiguredConstructionService(t, services.ConstructionConfig{NonceReservationTTL: time.Minute})
+	fullNodeMock.On("MpoolGetNonce", mock.Anything, sender).Return(signedTx.Message.Nonce, nil)
+	fullNodeMock.On("StateLookupID", mock.Anything, sender, filTypes.EmptyTSK).Return(address.Undef, filTypes.ErrActorNotFound)
+	fullNodeMock.On("GasEstimateMessageGas", mock.Anything, mock.Anything, mock.Anything, filTypes.EmptyTSK).
+		Return(func(_ context.Context, msg *filTypes.Message, _ *api.MessageSendSpec, _ filTypes.TipSetKey) *filTypes.Message {
+			estimated := *msg
+			estimated.GasLimit = 1000
+			return &estimated
+		}, nil)
+	fullNodeMock.On("GasEstimateGasPremium", mock.Anything, mock.Anything, sender, int64(1000), filTypes.EmptyTSK).
+		Return(abi.NewTokenAmount(100), nil).Maybe()
+	fullNodeMock.On("GasEstimateFeeCap", mock.Anything, mock.Anything, mock.Anything, filTypes.EmptyTSK).
+		Return(abi.NewTokenAmount(1000), nil).Maybe()
+	fullNodeMock.On("MpoolPush", mock.Anything, mock.Anything).Return(cid.Undef, errors.New("push failed"))
+
+	reserveNonce := func() uint64 {
+		resp, rosettaErr := c.ConstructionMetadata(ctx, &types.ConstructionMetadataRequest{
+			NetworkIdentifier: testNetwork,
+			Options:           map[string]interface{}{services.OptionsSenderIDKey: sender.String()},
+		})
+		require.Nil(t, rosettaErr)
+		return resp.Metadata[services.NonceKey].(uint64)
+	}
+
+	// The signed message holds the first reserved nonce
+	assert.Equal(t, signedTx.Message.Nonce, reserveNonce())
+	assert.Equal(t, signedTx.Message.Nonce+1, reserveNonce())
+
+	_, rosettaErr := c.ConstructionSubmit(ctx, &types.ConstructionSubmitRequest{
+		NetworkIdentifier: testNetwork,
+		SignedTransaction: signed,
+	})
+	require.NotNil(t, rosettaErr)
+	assert.Equal(t, rosetta.ErrUnableToSubmitTx.Code, rosettaErr.Code)
+
+	// The nonce of the message that failed to be pushed is handed out again
+	assert.Equal(t, signedTx.Message.Nonce, reserveNonce())
+}
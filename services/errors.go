@@ -0,0 +1,203 @@
+package services
+
+import (
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+var ErrMalformedParams = &types.Error{
+	Code:      1000,
+	Message:   "malformed params",
+	Retriable: false,
+}
+
+// ErrUnavailableOffline is returned by every endpoint that needs a Lotus node
+// when the proxy is running in offline mode
+var ErrUnavailableOffline = &types.Error{
+	Code:      1001,
+	Message:   "unavailable offline",
+	Retriable: false,
+}
+
+// ErrInvalidOperations is returned when the operations of a construction
+// request do not describe a transaction this proxy can build
+var ErrInvalidOperations = &types.Error{
+	Code:      1002,
+	Message:   "invalid operations",
+	Retriable: false,
+}
+
+// ErrMalformedTransaction is returned when a signed or unsigned transaction
+// can not be decoded
+var ErrMalformedTransaction = &types.Error{
+	Code:      1003,
+	Message:   "malformed transaction",
+	Retriable: false,
+}
+
+// ErrInvalidSignature is returned when a signature can not be attached to a
+// transaction or does not match it
+var ErrInvalidSignature = &types.Error{
+	Code:      1004,
+	Message:   "invalid signature",
+	Retriable: false,
+}
+
+// ErrInvalidPublicKey is returned when an address can not be derived from a
+// public key
+var ErrInvalidPublicKey = &types.Error{
+	Code:      1005,
+	Message:   "invalid public key",
+	Retriable: false,
+}
+
+// ErrInvalidSignatureType is returned when the signature type of a transaction
+// is unknown or can not sign for its sender
+var ErrInvalidSignatureType = &types.Error{
+	Code:      1006,
+	Message:   "invalid signature type",
+	Retriable: false,
+}
+
+// ErrNetworkMismatch is returned when a transaction uses addresses of a different
+// network than the one this proxy serves
+var ErrNetworkMismatch = &types.Error{
+	Code:      1007,
+	Message:   "network mismatch",
+	Retriable: false,
+}
+
+// ErrPreflightFailed is returned when the pre-flight simulation of a signed
+// transaction exits with a non-zero code, the details hold the execution result
+var ErrPreflightFailed = &types.Error{
+	Code:      1008,
+	Message:   "pre-flight simulation failed",
+	Retriable: false,
+}
+
+// ErrUnableToSimulateTx is returned when the pre-flight simulation of a signed
+// transaction can not be run
+var ErrUnableToSimulateTx = &types.Error{
+	Code:      1009,
+	Message:   "unable to simulate transaction",
+	Retriable: true,
+}
+
+// ErrWaitTimedOut is returned when a message is not included with the requested
+// confirmations before the wait timeout
+var ErrWaitTimedOut = &types.Error{
+	Code:      1010,
+	Message:   "message not included before timeout",
+	Retriable: true,
+}
+
+// ErrUnableToGetReceipt is returned when the receipt of a message can not be
+// retrieved from Lotus
+var ErrUnableToGetReceipt = &types.Error{
+	Code:      1011,
+	Message:   "unable to get message receipt",
+	Retriable: true,
+}
+
+// ErrInvalidOption is returned when an option of a construction request is
+// unknown or badly typed, the details name the rejected option
+var ErrInvalidOption = &types.Error{
+	Code:      1012,
+	Message:   "invalid option",
+	Retriable: false,
+}
+
+// ErrMessageNotPending is returned when a message to replace is not waiting in
+// the mempool
+var ErrMessageNotPending = &types.Error{
+	Code:      1013,
+	Message:   "message not pending",
+	Retriable: false,
+}
+
+// ErrInvalidCallParameters is returned when the parameters of a /call request
+// do not match the method schema, the details name the rejected parameter
+var ErrInvalidCallParameters = &types.Error{
+	Code:      1014,
+	Message:   "invalid call parameters",
+	Retriable: false,
+}
+
+// ErrMessageNotFound is returned when a message is not found on chain within
+// the searched epochs, it may still be in the mempool
+var ErrMessageNotFound = &types.Error{
+	Code:      1015,
+	Message:   "message not found on chain",
+	Retriable: true,
+}
+
+// ErrActorNotFound is returned when an actor does not exist at the requested tipset
+var ErrActorNotFound = &types.Error{
+	Code:      1016,
+	Message:   "actor not found",
+	Retriable: false,
+}
+
+// ErrUnableToGetActor is returned when the node fails to read an actor
+var ErrUnableToGetActor = &types.Error{
+	Code:      1017,
+	Message:   "unable to get actor",
+	Retriable: true,
+}
+
+// ErrExecutionReverted is returned when an Ethereum call reverts, the details
+// hold the revert data and reason
+var ErrExecutionReverted = &types.Error{
+	Code:      1018,
+	Message:   "execution reverted",
+	Retriable: false,
+}
+
+// ErrUnableToQueryEth is returned when a Lotus Ethereum API call fails
+var ErrUnableToQueryEth = &types.Error{
+	Code:      1019,
+	Message:   "unable to query the ethereum api",
+	Retriable: true,
+}
+
+// ErrInvalidSubAccount is returned when a sub-account is not supported for
+// the requested account
+var ErrInvalidSubAccount = &types.Error{
+	Code:      1020,
+	Message:   "invalid sub-account",
+	Retriable: false,
+}
+
+// ErrUnableToGetMempool is returned when the pending messages of the mempool
+// can not be read
+var ErrUnableToGetMempool = &types.Error{
+	Code:      1021,
+	Message:   "unable to get the mempool",
+	Retriable: true,
+}
+
+// ErrorList contains the errors defined by this proxy, on top of the ones
+// defined upstream
+var ErrorList = []*types.Error{
+	ErrMalformedParams,
+	ErrUnavailableOffline,
+	ErrInvalidOperations,
+	ErrMalformedTransaction,
+	ErrInvalidSignature,
+	ErrInvalidPublicKey,
+	ErrInvalidSignatureType,
+	ErrNetworkMismatch,
+	ErrPreflightFailed,
+	ErrUnableToSimulateTx,
+	ErrWaitTimedOut,
+	ErrUnableToGetReceipt,
+	ErrInvalidOption,
+	ErrMessageNotPending,
+	ErrInvalidCallParameters,
+	ErrMessageNotFound,
+	ErrActorNotFound,
+	ErrUnableToGetActor,
+	ErrExecutionReverted,
+	ErrUnableToQueryEth,
+	ErrInvalidSubAccount,
+	ErrUnableToGetMempool,
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"encoding/json"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/api"
+)
+
+// MinerLockedFunds are the fields of the miner actor state holding locked funds
+type MinerLockedFunds struct {
+	// LockedFunds are the vesting block rewards
+	LockedFunds       abi.TokenAmount
+	PreCommitDeposits abi.TokenAmount
+	InitialPledge     abi.TokenAmount
+	FeeDebt           abi.TokenAmount
+}
+
+// Total returns the sum of the vesting funds, pre-commit deposits and initial pledge
+func (m MinerLockedFunds) Total() abi.TokenAmount {
+	return big.Sum(m.LockedFunds, m.PreCommitDeposits, m.InitialPledge)
+}
+
+// ReadMinerLockedFunds reads the locked funds from the state of a miner actor,
+// as returned by StateReadState
+func ReadMinerLockedFunds(state *api.ActorState) (*MinerLockedFunds, error) {
+	locked := &MinerLockedFunds{
+		LockedFunds:       big.Zero(),
+		PreCommitDeposits: big.Zero(),
+		InitialPledge:     big.Zero(),
+		FeeDebt:           big.Zero(),
+	}
+
+	raw, err := json.Marshal(state.State)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(raw, locked); err != nil {
+		return nil, err
+	}
+
+	return locked, nil
+}
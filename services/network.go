@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tools"
+)
+
+const (
+	// OperationStatusOk is the status of operations whose message was successfully executed
+	OperationStatusOk = "Ok"
+
+	// OperationStatusFailed is the status of operations whose message failed
+	OperationStatusFailed = "Fail"
+)
+
+// NetworkAPIService implements the server.NetworkAPIServicer interface.
+// Requests are forwarded to the upstream service, which needs a Lotus node.
+// Without one (offline mode) the list and options are built locally.
+type NetworkAPIService struct {
+	upstream            server.NetworkAPIServicer
+	network             *types.NetworkIdentifier
+	supportedOperations []string
+	callMethods         []string
+}
+
+// NewNetworkAPIService creates a new instance of a NetworkAPIService.
+// A nil upstream service makes it run offline.
+func NewNetworkAPIService(network *types.NetworkIdentifier, upstream server.NetworkAPIServicer, supportedOperations []string,
+	callMethods []string) server.NetworkAPIServicer {
+	return &NetworkAPIService{
+		upstream:            upstream,
+		network:             network,
+		supportedOperations: supportedOperations,
+		callMethods:         callMethods,
+	}
+}
+
+// NetworkList implements the /network/list endpoint.
+func (s *NetworkAPIService) NetworkList(
+	ctx context.Context,
+	request *types.MetadataRequest,
+) (*types.NetworkListResponse, *types.Error) {
+	if s.upstream != nil {
+		return s.upstream.NetworkList(ctx, request)
+	}
+
+	return &types.NetworkListResponse{
+		NetworkIdentifiers: []*types.NetworkIdentifier{s.network},
+	}, nil
+}
+
+// NetworkOptions implements the /network/options endpoint.
+func (s *NetworkAPIService) NetworkOptions(
+	ctx context.Context,
+	request *types.NetworkRequest,
+) (*types.NetworkOptionsResponse, *types.Error) {
+	if s.upstream == nil {
+		return s.offlineNetworkOptions(), nil
+	}
+
+	resp, err := s.upstream.NetworkOptions(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Advertise the operations, errors and call methods defined by this proxy too
+	if resp.Allow != nil {
+		resp.Allow.OperationTypes = mergeOperationTypes(resp.Allow.OperationTypes, s.supportedOperations)
+		resp.Allow.Errors = append(resp.Allow.Errors, ErrorList...)
+		resp.Allow.CallMethods = s.callMethods
+	}
+
+	return resp, nil
+}
+
+// mergeOperationTypes appends the operations missing from the upstream list
+func mergeOperationTypes(upstream []string, supported []string) []string {
+	seen := make(map[string]bool, len(upstream))
+	for _, op := range upstream {
+		seen[op] = true
+	}
+
+	merged := upstream
+	for _, op := range supported {
+		if !seen[op] {
+			seen[op] = true
+			merged = append(merged, op)
+		}
+	}
+	return merged
+}
+
+// NetworkStatus implements the /network/status endpoint.
+func (s *NetworkAPIService) NetworkStatus(
+	ctx context.Context,
+	request *types.NetworkRequest,
+) (*types.NetworkStatusResponse, *types.Error) {
+	if s.upstream == nil {
+		return nil, ErrUnavailableOffline
+	}
+
+	return s.upstream.NetworkStatus(ctx, request)
+}
+
+func (s *NetworkAPIService) offlineNetworkOptions() *types.NetworkOptionsResponse {
+	middlewareVersion := tools.GitRevision
+
+	return &types.NetworkOptionsResponse{
+		Version: &types.Version{
+			RosettaVersion:    types.RosettaAPIVersion,
+			NodeVersion:       tools.LotusVersion,
+			MiddlewareVersion: &middlewareVersion,
+		},
+		Allow: &types.Allow{
+			OperationStatuses: []*types.OperationStatus{
+				{
+					Status:     OperationStatusOk,
+					Successful: true,
+				},
+				{
+					Status:     OperationStatusFailed,
+					Successful: false,
+				},
+			},
+			OperationTypes: s.supportedOperations,
+			Errors:         ErrorList,
+			CallMethods:    s.callMethods,
+		},
+	}
+}
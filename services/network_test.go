@@ -0,0 +1,40 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coinbase/rosetta-sdk-go/server"
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+)
+
+// upstreamNetworkService answers /network/options with a fixed response
+type upstreamNetworkService struct {
+	server.NetworkAPIServicer
+	options *types.NetworkOptionsResponse
+}
+
+func (u *upstreamNetworkService) NetworkOptions(context.Context, *types.NetworkRequest) (*types.NetworkOptionsResponse, *types.Error) {
+	return u.options, nil
+}
+
+func TestNetworkOptionsOnline(t *testing.T) {
+	upstream := &upstreamNetworkService{
+		options: &types.NetworkOptionsResponse{
+			Allow: &types.Allow{
+				OperationTypes: []string{services.SendOperationType, "Fee"},
+			},
+		},
+	}
+	s := services.NewNetworkAPIService(testNetwork, upstream,
+		[]string{services.SendOperationType, services.ProposeOperationType}, []string{"estimateGas"})
+
+	resp, rosettaErr := s.NetworkOptions(context.Background(), &types.NetworkRequest{NetworkIdentifier: testNetwork})
+	require.Nil(t, rosettaErr)
+	assert.Equal(t, []string{services.SendOperationType, "Fee", services.ProposeOperationType}, resp.Allow.OperationTypes)
+	assert.Equal(t, services.ErrorList, resp.Allow.Errors)
+	assert.Equal(t, []string{"estimateGas"}, resp.Allow.CallMethods)
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+)
+
+// NonceManager hands out consecutive nonces to constructions running in parallel
+// for the same sender, which would otherwise all get the next mempool nonce.
+// A reservation is released once the mempool nonce moves past it, meaning its
+// message was pushed or included, or after its TTL so abandoned constructions
+// do not leave gaps behind. Senders are keyed by their ID address when they
+// have one, so every form of an address shares the same reservations.
+type NonceManager struct {
+	node api.FullNode
+	ttl  time.Duration
+	now  func() time.Time
+
+	lock         sync.Mutex
+	reservations map[address.Address]map[uint64]time.Time
+}
+
+// NewNonceManager creates a NonceManager whose reservations expire after ttl
+func NewNonceManager(node api.FullNode, ttl time.Duration) *NonceManager {
+	return NewNonceManagerWithClock(node, ttl, time.Now)
+}
+
+// NewNonceManagerWithClock creates a NonceManager reading the time from now
+func NewNonceManagerWithClock(node api.FullNode, ttl time.Duration, now func() time.Time) *NonceManager {
+	return &NonceManager{
+		node:         node,
+		ttl:          ttl,
+		now:          now,
+		reservations: make(map[address.Address]map[uint64]time.Time),
+	}
+}
+
+// Reserve returns the lowest nonce of sender that is neither used in the
+// mempool or on chain nor reserved by another construction
+func (m *NonceManager) Reserve(ctx context.Context, sender address.Address) (uint64, error) {
+	mpoolNonce, err := m.node.MpoolGetNonce(ctx, sender)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := m.senderKey(ctx, sender)
+	if err != nil {
+		return 0, err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := m.now()
+	m.pruneExpired(now)
+
+	reserved := m.reservations[key]
+	if reserved == nil {
+		reserved = make(map[uint64]time.Time)
+		m.reservations[key] = reserved
+	}
+
+	// Reservations the mempool moved past belong to pushed or included messages
+	for nonce := range reserved {
+		if nonce < mpoolNonce {
+			delete(reserved, nonce)
+		}
+	}
+
+	nonce := mpoolNonce
+	for {
+		if _, ok := reserved[nonce]; !ok {
+			break
+		}
+		nonce++
+	}
+
+	reserved[nonce] = now.Add(m.ttl)
+	return nonce, nil
+}
+
+// Release frees the reservation of nonce for sender, so the next construction
+// gets it again. It is called when a signed message fails to be submitted.
+func (m *NonceManager) Release(ctx context.Context, sender address.Address, nonce uint64) error {
+	key, err := m.senderKey(ctx, sender)
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	reserved := m.reservations[key]
+	delete(reserved, nonce)
+	if len(reserved) == 0 {
+		delete(m.reservations, key)
+	}
+	return nil
+}
+
+// senderKey returns the ID address of sender, or sender itself when it has no
+// actor on chain yet
+func (m *NonceManager) senderKey(ctx context.Context, sender address.Address) (address.Address, error) {
+	if sender.Protocol() == address.ID {
+		return sender, nil
+	}
+
+	id, err := m.node.StateLookupID(ctx, sender, filTypes.EmptyTSK)
+	if err != nil {
+		if strings.Contains(err.Error(), filTypes.ErrActorNotFound.Error()) {
+			return sender, nil
+		}
+		return address.Undef, err
+	}
+	return id, nil
+}
+
+// pruneExpired drops the reservations of every sender whose TTL is over. Must
+// be called with the lock held.
+func (m *NonceManager) pruneExpired(now time.Time) {
+	for sender, reserved := range m.reservations {
+		for nonce, expiration := range reserved {
+			if !now.Before(expiration) {
+				delete(reserved, nonce)
+			}
+		}
+
+		if len(reserved) == 0 {
+			delete(m.reservations, sender)
+		}
+	}
+}
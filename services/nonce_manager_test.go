@@ -0,0 +1,125 @@
+package services_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/services"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tests/mocks"
+)
+
+// testClock is a clock only moving when a test sets it
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestNonceManagerConcurrentReservations(t *testing.T) {
+	sender, err := address.NewFromString(testReceiver)
+	require.NoError(t, err)
+
+	fullNodeMock := mocks.NewFullNode(t)
+	fullNodeMock.On("MpoolGetNonce", mock.Anything, sender).Return(uint64(7), nil)
+	fullNodeMock.On("StateLookupID", mock.Anything, sender, filTypes.EmptyTSK).Return(address.Undef, filTypes.ErrActorNotFound)
+	manager := services.NewNonceManager(fullNodeMock, time.Minute)
+
+	const constructions = 20
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		nonces = make(map[uint64]bool)
+	)
+	for i := 0; i < constructions; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := manager.Reserve(context.Background(), sender)
+			assert.NoError(t, err)
+
+			lock.Lock()
+			defer lock.Unlock()
+			nonces[nonce] = true
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, nonces, constructions)
+	for nonce := uint64(7); nonce < 7+constructions; nonce++ {
+		assert.True(t, nonces[nonce], "nonce %d was not handed out", nonce)
+	}
+}
+
+func TestNonceManagerReconcilesWithMempool(t *testing.T) {
+	sender, err := address.NewFromString(testReceiver)
+	require.NoError(t, err)
+
+	fullNodeMock := mocks.NewFullNode(t)
+	fullNodeMock.On("MpoolGetNonce", mock.Anything, sender).Return(uint64(1), nil).Times(3)
+	fullNodeMock.On("MpoolGetNonce", mock.Anything, sender).Return(uint64(2), nil).Once()
+	fullNodeMock.On("StateLookupID", mock.Anything, sender, filTypes.EmptyTSK).Return(address.Undef, filTypes.ErrActorNotFound)
+	clock := &testClock{now: time.Unix(1700000000, 0)}
+	manager := services.NewNonceManagerWithClock(fullNodeMock, time.Minute, clock.Now)
+
+	for expected := uint64(1); expected <= 3; expected++ {
+		nonce, err := manager.Reserve(context.Background(), sender)
+		require.NoError(t, err)
+		assert.Equal(t, expected, nonce)
+	}
+
+	// Nonce 1 was pushed and 2, 3 expired, so 2 is handed out again
+	clock.now = clock.now.Add(time.Minute)
+	nonce, err := manager.Reserve(context.Background(), sender)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), nonce)
+}
+
+func TestNonceManagerRelease(t *testing.T) {
+	sender, err := address.NewFromString(testReceiver)
+	require.NoError(t, err)
+
+	fullNodeMock := mocks.NewFullNode(t)
+	fullNodeMock.On("MpoolGetNonce", mock.Anything, sender).Return(uint64(4), nil)
+	fullNodeMock.On("StateLookupID", mock.Anything, sender, filTypes.EmptyTSK).Return(address.Undef, filTypes.ErrActorNotFound)
+	manager := services.NewNonceManager(fullNodeMock, time.Minute)
+
+	for expected := uint64(4); expected <= 5; expected++ {
+		nonce, err := manager.Reserve(context.Background(), sender)
+		require.NoError(t, err)
+		assert.Equal(t, expected, nonce)
+	}
+
+	require.NoError(t, manager.Release(context.Background(), sender, 4))
+	nonce, err := manager.Reserve(context.Background(), sender)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), nonce)
+}
+
+func TestNonceManagerAddressForms(t *testing.T) {
+	sender, err := address.NewFromString(testReceiver)
+	require.NoError(t, err)
+	senderID, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+
+	fullNodeMock := mocks.NewFullNode(t)
+	fullNodeMock.On("MpoolGetNonce", mock.Anything, mock.Anything).Return(uint64(9), nil)
+	fullNodeMock.On("StateLookupID", mock.Anything, sender, filTypes.EmptyTSK).Return(senderID, nil)
+	manager := services.NewNonceManager(fullNodeMock, time.Minute)
+
+	nonce, err := manager.Reserve(context.Background(), sender)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), nonce)
+
+	nonce, err = manager.Reserve(context.Background(), senderID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), nonce)
+}
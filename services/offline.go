@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+)
+
+// OfflineAPIService answers every request of the account, block, mempool and call
+// APIs with ErrUnavailableOffline. It is used in place of those services when the
+// proxy runs without a Lotus node.
+type OfflineAPIService struct{}
+
+// NewOfflineAPIService creates a new instance of an OfflineAPIService.
+func NewOfflineAPIService() *OfflineAPIService {
+	return &OfflineAPIService{}
+}
+
+// AccountBalance implements the /account/balance endpoint.
+func (s *OfflineAPIService) AccountBalance(
+	ctx context.Context,
+	request *types.AccountBalanceRequest,
+) (*types.AccountBalanceResponse, *types.Error) {
+	return nil, ErrUnavailableOffline
+}
+
+// AccountCoins implements the /account/coins endpoint.
+func (s *OfflineAPIService) AccountCoins(
+	ctx context.Context,
+	request *types.AccountCoinsRequest,
+) (*types.AccountCoinsResponse, *types.Error) {
+	return nil, ErrUnavailableOffline
+}
+
+// Block implements the /block endpoint.
+func (s *OfflineAPIService) Block(
+	ctx context.Context,
+	request *types.BlockRequest,
+) (*types.BlockResponse, *types.Error) {
+	return nil, ErrUnavailableOffline
+}
+
+// BlockTransaction implements the /block/transaction endpoint.
+func (s *OfflineAPIService) BlockTransaction(
+	ctx context.Context,
+	request *types.BlockTransactionRequest,
+) (*types.BlockTransactionResponse, *types.Error) {
+	return nil, ErrUnavailableOffline
+}
+
+// Mempool implements the /mempool endpoint.
+func (s *OfflineAPIService) Mempool(
+	ctx context.Context,
+	request *types.NetworkRequest,
+) (*types.MempoolResponse, *types.Error) {
+	return nil, ErrUnavailableOffline
+}
+
+// MempoolTransaction implements the /mempool/transaction endpoint.
+func (s *OfflineAPIService) MempoolTransaction(
+	ctx context.Context,
+	request *types.MempoolTransactionRequest,
+) (*types.MempoolTransactionResponse, *types.Error) {
+	return nil, ErrUnavailableOffline
+}
+
+// Call implements the /call endpoint.
+func (s *OfflineAPIService) Call(
+	ctx context.Context,
+	request *types.CallRequest,
+) (*types.CallResponse, *types.Error) {
+	return nil, ErrUnavailableOffline
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/crypto"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+// Signed messages can be submitted either as the JSON produced by
+// /construction/combine or as the CBOR encoding produced by most filecoin
+// signers, hex or base64 encoded.
+//
+// Decoded addresses carry no network, so a mismatch is detected from what the
+// message still holds: the prefixes of JSON addresses, and the chain id signed
+// by delegated senders, whatever the encoding. Other CBOR messages signed for
+// another network are only rejected by Lotus.
+
+// jsonSignedMessageAddresses holds the raw addresses of a JSON signed message,
+// which keep the network prefix lost once decoded
+type jsonSignedMessageAddresses struct {
+	Message struct {
+		From string
+		To   string
+	}
+}
+
+// decodeSignedMessage detects the encoding of a signed message, decodes and validates it
+func decodeSignedMessage(network *types.NetworkIdentifier, rawTx string) (*filTypes.SignedMessage, *types.Error) {
+	rawTx = strings.TrimSpace(rawTx)
+
+	var signedTx *filTypes.SignedMessage
+	if strings.HasPrefix(rawTx, "{") {
+		var errJson *types.Error
+		signedTx, errJson = decodeJSONSignedMessage(network, rawTx)
+		if errJson != nil {
+			return nil, errJson
+		}
+	} else {
+		var err error
+		signedTx, err = decodeCBORSignedMessage(rawTx)
+		if err != nil {
+			return nil, rosetta.BuildError(ErrMalformedTransaction, err, false)
+		}
+	}
+
+	errValidation := validateSignedMessage(network, signedTx)
+	if errValidation != nil {
+		return nil, errValidation
+	}
+
+	return signedTx, nil
+}
+
+func decodeJSONSignedMessage(network *types.NetworkIdentifier, rawTx string) (*filTypes.SignedMessage, *types.Error) {
+	var addrs jsonSignedMessageAddresses
+	err := json.Unmarshal([]byte(rawTx), &addrs)
+	if err != nil {
+		return nil, rosetta.BuildError(ErrMalformedTransaction, err, false)
+	}
+
+	for _, addr := range []string{addrs.Message.From, addrs.Message.To} {
+		if addr == "" {
+			return nil, rosetta.BuildError(rosetta.ErrInvalidAccountAddress, fmt.Errorf("message sender and receiver are required"), false)
+		}
+		if _, err = address.NewFromString(addr); err != nil {
+			return nil, rosetta.BuildError(rosetta.ErrInvalidAccountAddress, fmt.Errorf("invalid address %s: %w", addr, err), false)
+		}
+		if !hasNetworkPrefix(network, addr) {
+			return nil, rosetta.BuildError(ErrNetworkMismatch, fmt.Errorf("address %s does not belong to %s", addr, network.Network), false)
+		}
+	}
+
+	var signedTx filTypes.SignedMessage
+	err = json.Unmarshal([]byte(rawTx), &signedTx)
+	if err != nil {
+		return nil, rosetta.BuildError(ErrMalformedTransaction, err, false)
+	}
+
+	return &signedTx, nil
+}
+
+// decodeCBORSignedMessage decodes a hex, with or without 0x prefix, or base64
+// encoded CBOR signed message. Some strings are both valid hex and base64, so
+// every decoding is tried until one holds a signed message.
+func decodeCBORSignedMessage(rawTx string) (*filTypes.SignedMessage, error) {
+	candidates := decodeTransactionBytes(rawTx)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("signed transaction is neither JSON nor hex or base64 encoded CBOR")
+	}
+
+	var errs []string
+	for _, candidate := range candidates {
+		signedTx, err := filTypes.DecodeSignedMessage(candidate.bytes)
+		if err == nil {
+			return signedTx, nil
+		}
+		errs = append(errs, fmt.Sprintf("as %s: %v", candidate.encoding, err))
+	}
+
+	return nil, fmt.Errorf("signed transaction is not a CBOR signed message (%s)", strings.Join(errs, "; "))
+}
+
+// encodedBytes are the bytes decoded from a string with an encoding
+type encodedBytes struct {
+	encoding string
+	bytes    []byte
+}
+
+// decodeTransactionBytes returns every decoding of rawTx among hex, with or
+// without 0x prefix, and base64. Prefixed hex is not ambiguous.
+func decodeTransactionBytes(rawTx string) []encodedBytes {
+	if strings.HasPrefix(rawTx, "0x") {
+		if decoded, err := hex.DecodeString(rawTx[2:]); err == nil {
+			return []encodedBytes{{encoding: "hex", bytes: decoded}}
+		}
+	}
+
+	var candidates []encodedBytes
+	if decoded, err := hex.DecodeString(rawTx); err == nil {
+		candidates = append(candidates, encodedBytes{encoding: "hex", bytes: decoded})
+	}
+
+	if decoded, err := base64.StdEncoding.DecodeString(rawTx); err == nil {
+		candidates = append(candidates, encodedBytes{encoding: "base64", bytes: decoded})
+	} else if decoded, err := base64.RawStdEncoding.DecodeString(rawTx); err == nil {
+		candidates = append(candidates, encodedBytes{encoding: "base64", bytes: decoded})
+	}
+
+	return candidates
+}
+
+// validateSignedMessage checks the parts of a signed message that Lotus only
+// reports as a generic failure
+func validateSignedMessage(network *types.NetworkIdentifier, signedTx *filTypes.SignedMessage) *types.Error {
+	message := &signedTx.Message
+	if message.From == address.Undef || message.To == address.Undef {
+		return rosetta.BuildError(rosetta.ErrInvalidAccountAddress, fmt.Errorf("message sender and receiver are required"), false)
+	}
+
+	if len(signedTx.Signature.Data) == 0 {
+		return rosetta.BuildError(ErrInvalidSignature, fmt.Errorf("missing signature"), false)
+	}
+
+	var validSender bool
+	switch signedTx.Signature.Type {
+	case crypto.SigTypeSecp256k1:
+		validSender = message.From.Protocol() == address.SECP256K1 || message.From.Protocol() == address.ID
+	case crypto.SigTypeBLS:
+		validSender = message.From.Protocol() == address.BLS || message.From.Protocol() == address.ID
+	case crypto.SigTypeDelegated:
+		validSender = isDelegated(message.From)
+	default:
+		return rosetta.BuildError(ErrInvalidSignatureType, fmt.Errorf("unknown signature type %d", signedTx.Signature.Type), false)
+	}
+
+	if !validSender {
+		return rosetta.BuildError(ErrInvalidSignatureType,
+			fmt.Errorf("signature type %d can not sign for %s", signedTx.Signature.Type, message.From), false)
+	}
+
+	if signedTx.Signature.Type == crypto.SigTypeDelegated {
+		err := verifyDelegatedSignature(network, signedTx)
+		if err != nil {
+			if signedFor, ok := delegatedSignatureNetwork(signedTx); ok {
+				return rosetta.BuildError(ErrNetworkMismatch,
+					fmt.Errorf("message was signed for %s, not for %s", signedFor, network.Network), false)
+			}
+			return rosetta.BuildError(ErrInvalidSignature, err, false)
+		}
+	}
+
+	return nil
+}
+
+// hasNetworkPrefix reports whether addr can belong to the network this proxy
+// serves. Besides the network's own prefix, the one of the addresses this proxy
+// encodes is accepted, as its JSON output uses the build's network whatever
+// network it serves.
+func hasNetworkPrefix(network *types.NetworkIdentifier, addr string) bool {
+	return strings.HasPrefix(addr, networkPrefix(addressNetwork(network))) ||
+		strings.HasPrefix(addr, networkPrefix(address.CurrentNetwork))
+}
+
+// networkPrefix returns the prefix of the addresses of an address network
+func networkPrefix(network address.Network) string {
+	if network == address.Mainnet {
+		return address.MainnetPrefix
+	}
+	return address.TestnetPrefix
+}
+
+// delegatedSignatureNetwork returns the network, other than the served one, a
+// delegated signature is valid for
+func delegatedSignatureNetwork(signedTx *filTypes.SignedMessage) (string, bool) {
+	networks := make([]string, 0, len(eip155ChainIDs))
+	for name := range eip155ChainIDs {
+		networks = append(networks, name)
+	}
+	sort.Strings(networks)
+
+	for _, name := range networks {
+		if verifyDelegatedSignature(&types.NetworkIdentifier{Network: name}, signedTx) == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
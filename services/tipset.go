@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+	rosettaTools "github.com/zondax/rosetta-filecoin-proxy/rosetta/tools"
+)
+
+// ResolveTipSet returns the tipset of a block identifier, or the head tipset
+// when it is nil. The hash can not be resolved on its own and is only checked
+// against the tipset found at the index.
+func ResolveTipSet(ctx context.Context, node api.FullNode,
+	blockId *types.PartialBlockIdentifier) (*filTypes.TipSet, *types.Error) {
+	var (
+		tipSet *filTypes.TipSet
+		err    error
+	)
+
+	if blockId == nil || (blockId.Index == nil && blockId.Hash == nil) {
+		impl := func() {
+			tipSet, err = node.ChainHead(ctx)
+		}
+
+		errTimeOut := rosettaTools.WrapWithTimeout(impl, LotusCallTimeOut)
+		if errTimeOut != nil {
+			return nil, rosetta.ErrLotusCallTimedOut
+		}
+		if err != nil {
+			return nil, rosetta.BuildError(rosetta.ErrUnableToGetTipset, err, true)
+		}
+		return tipSet, nil
+	}
+
+	if blockId.Index == nil {
+		return nil, rosetta.BuildError(rosetta.ErrInsufficientQueryInputs, nil, false)
+	}
+
+	requestedHeight := *blockId.Index
+	if requestedHeight < 0 {
+		return nil, rosetta.BuildError(rosetta.ErrMalformedValue, fmt.Errorf("index must not be negative"), false)
+	}
+
+	impl := func() {
+		tipSet, err = node.ChainGetTipSetByHeight(ctx, abi.ChainEpoch(requestedHeight), filTypes.EmptyTSK)
+	}
+
+	errTimeOut := rosettaTools.WrapWithTimeout(impl, LotusCallTimeOut)
+	if errTimeOut != nil {
+		return nil, rosetta.ErrLotusCallTimedOut
+	}
+	if err != nil {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTipset, err, true)
+	}
+
+	// Lotus returns the previous tipset for null rounds
+	if int64(tipSet.Height()) != requestedHeight {
+		return nil, rosetta.BuildError(rosetta.ErrUnableToGetTipset,
+			fmt.Errorf("epoch %d is a null round", requestedHeight), false)
+	}
+
+	if blockId.Hash != nil {
+		tipSetKeyHash, encErr := rosetta.BuildTipSetKeyHash(tipSet.Key())
+		if encErr != nil {
+			return nil, rosetta.BuildError(rosetta.ErrUnableToBuildTipSetHash, encErr, true)
+		}
+		if *tipSetKeyHash != *blockId.Hash {
+			return nil, rosetta.BuildError(rosetta.ErrInvalidHash, nil, false)
+		}
+	}
+
+	return tipSet, nil
+}
@@ -0,0 +1,77 @@
+// Package fixtures holds the chain fixtures shared by the tests of the services
+package fixtures
+
+import (
+	"testing"
+
+	rosettaTypes "github.com/coinbase/rosetta-sdk-go/types"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/lotus/api"
+	filTypes "github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/zondax/filecoin-indexing-rosetta-proxy/tests/mocks"
+	rosetta "github.com/zondax/rosetta-filecoin-proxy/rosetta/services"
+)
+
+const (
+	// MessageCid is a valid message CID, also used for the links of TipSet blocks
+	MessageCid = "bafy2bzacebbpdegvr3i4cosewthysg5xkxpqfn2wfcz6mv2hmoktwbdxkax4s"
+
+	// GenesisTimestamp is the timestamp of the genesis of the TipSet chain
+	GenesisTimestamp = 1598306400
+
+	// BlockDelay is the time in seconds between two TipSet epochs
+	BlockDelay = 30
+)
+
+// Network is the network the services are created for
+var Network = &rosettaTypes.NetworkIdentifier{
+	Blockchain: rosetta.BlockChainName,
+	Network:    "mainnet",
+}
+
+// NewFullNode returns a Lotus node mock answering the network name of Network
+// and reporting a completed sync
+func NewFullNode(t *testing.T) *mocks.FullNode {
+	fullNodeMock := mocks.NewFullNode(t)
+	fullNodeMock.On("StateNetworkName", mock.Anything).Return(dtypes.NetworkName(Network.Network), nil).Maybe()
+	fullNodeMock.On("SyncState", mock.Anything).Return(&api.SyncState{
+		ActiveSyncs: []api.ActiveSync{{Stage: api.StageSyncComplete}},
+	}, nil).Maybe()
+	return fullNodeMock
+}
+
+// TipSet returns a tipset at height, produced on time
+func TipSet(t *testing.T, height abi.ChainEpoch) *filTypes.TipSet {
+	t.Helper()
+
+	testCid, err := cid.Decode(MessageCid)
+	require.NoError(t, err)
+	miner, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+
+	ts, err := filTypes.NewTipSet([]*filTypes.BlockHeader{
+		{
+			Miner:                 miner,
+			Ticket:                &filTypes.Ticket{VRFProof: []byte("vrf proof0000000vrf proof0000000")},
+			ElectionProof:         &filTypes.ElectionProof{VRFProof: []byte("vrf proof0000000vrf proof0000000")},
+			Parents:               []cid.Cid{testCid},
+			ParentMessageReceipts: testCid,
+			BLSAggregate:          &crypto.Signature{Type: crypto.SigTypeBLS},
+			ParentWeight:          filTypes.NewInt(1),
+			Messages:              testCid,
+			Height:                height,
+			Timestamp:             GenesisTimestamp + uint64(height)*BlockDelay,
+			ParentStateRoot:       testCid,
+			BlockSig:              &crypto.Signature{Type: crypto.SigTypeBLS},
+			ParentBaseFee:         filTypes.NewInt(100),
+		},
+	})
+	require.NoError(t, err)
+	return ts
+}